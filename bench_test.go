@@ -23,6 +23,13 @@ var (
 	bench3Float func(float64, float64, float64) float64
 	benchMixed  func(int64, float64, int64, float64) float64
 
+	// signatures without a typed path that go through reflect.MakeFunc
+	benchString      func(string, int64) int64
+	benchMixedInt32s func(int32, float32, int32, float64) int64
+
+	// calls a callback n times
+	benchCallback func(cb uintptr, n int64) int64
+
 	// Direct syscall function pointers (for baseline comparison)
 	benchNoopSym uintptr
 	bench1IntSym uintptr
@@ -56,6 +63,9 @@ func setupBenchLib(b *testing.B) {
 	purego.RegisterLibFunc(&bench1Float, benchLib, "bench_1float")
 	purego.RegisterLibFunc(&bench3Float, benchLib, "bench_3float")
 	purego.RegisterLibFunc(&benchMixed, benchLib, "bench_mixed")
+	purego.RegisterLibFunc(&benchString, benchLib, "bench_string")
+	purego.RegisterLibFunc(&benchMixedInt32s, benchLib, "mixed_int_float_double")
+	purego.RegisterLibFunc(&benchCallback, benchLib, "bench_callback")
 
 	// Direct syscall symbols for raw performance comparison
 	benchNoopSym, _ = purego.Dlsym(benchLib, "bench_noop")
@@ -164,6 +174,63 @@ func BenchmarkCallMixed(b *testing.B) {
 	}
 }
 
+func BenchmarkCallString(b *testing.B) {
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		b.Skip("benchmark requires amd64 or arm64")
+	}
+	setupBenchLib(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = benchString("purego\x00", 1)
+	}
+}
+
+func BenchmarkCallMixedInt32s(b *testing.B) {
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		b.Skip("benchmark requires amd64 or arm64")
+	}
+	setupBenchLib(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = benchMixedInt32s(1, 0.5, 2, 3)
+	}
+}
+
+// Callback benchmarks - C calls the Go callback b.N times
+
+func benchCompare(a, b int64) int64 {
+	return a - b
+}
+
+func BenchmarkCallback(b *testing.B) {
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		b.Skip("benchmark requires amd64 or arm64")
+	}
+	setupBenchLib(b)
+	cb := purego.NewCallback(benchCompare)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	_ = benchCallback(cb, int64(b.N))
+}
+
+func BenchmarkCallbackTyped(b *testing.B) {
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		b.Skip("benchmark requires amd64 or arm64")
+	}
+	setupBenchLib(b)
+	cb := purego.NewCallback2(benchCompare)
+	defer cb.Release()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	_ = benchCallback(cb.Ptr(), int64(b.N))
+}
+
 // Direct Syscall benchmarks - raw performance baseline (zero allocations)
 
 func BenchmarkSyscall0(b *testing.B) {
@@ -0,0 +1,499 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build darwin || freebsd || linux || netbsd || windows
+
+package purego
+
+import (
+	"math"
+	"reflect"
+	"runtime"
+	"unsafe"
+
+	"github.com/ebitengine/purego/internal/strings"
+)
+
+// BindType is the set of types that the Bind functions pass to and return from C without reflection.
+// Integers, bool and unsafe.Pointer are passed in integer registers, float32 and float64 in
+// floating-point registers and a string is passed as a null-terminated char*.
+type BindType interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64 | ~bool | ~string | unsafe.Pointer
+}
+
+// binder calls a C function with arguments whose kinds are known when the function is bound.
+type binder struct {
+	cfn   uintptr
+	kinds [8]reflect.Kind // the kinds of the arguments
+	ret   reflect.Kind    // the kind of the result or reflect.Invalid for void
+}
+
+// bindCall holds the state of a single call to a bound function.
+type bindCall struct {
+	ints, floats [8]uintptr
+	numInts      int
+	numFloats    int
+	strs         [8]*byte // C strings that must stay alive during the call
+	r1, f1       uintptr
+}
+
+func typeFor[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// newBinder returns a binder for cfn or nil if the platform needs the reflection based RegisterFunc.
+func newBinder(cfn uintptr, ret reflect.Type, args ...reflect.Type) *binder {
+	if cfn == 0 {
+		panic("purego: cfn is nil")
+	}
+	// the trampolines of these platforms take the integer and floating-point arguments separately
+	if runtime.GOOS == "windows" || !stackArgsUnlimited() {
+		return nil
+	}
+	b := &binder{cfn: cfn}
+	for i, arg := range args {
+		b.kinds[i] = arg.Kind()
+	}
+	if ret != nil {
+		b.ret = ret.Kind()
+	}
+	return b
+}
+
+// arg places the argument i that p points to.
+func (b *binder) arg(c *bindCall, i int, p unsafe.Pointer) {
+	var x uintptr
+	switch b.kinds[i] {
+	case reflect.Float32:
+		c.floats[c.numFloats] = uintptr(math.Float32bits(*(*float32)(p)))
+		c.numFloats++
+		return
+	case reflect.Float64:
+		c.floats[c.numFloats] = uintptr(math.Float64bits(*(*float64)(p)))
+		c.numFloats++
+		return
+	case reflect.String:
+		c.strs[i] = strings.CString(*(*string)(p))
+		x = uintptr(unsafe.Pointer(c.strs[i]))
+	case reflect.Bool:
+		if *(*bool)(p) {
+			x = 1
+		}
+	case reflect.Int8:
+		x = uintptr(*(*int8)(p))
+	case reflect.Int16:
+		x = uintptr(*(*int16)(p))
+	case reflect.Int32:
+		x = uintptr(*(*int32)(p))
+	case reflect.Uint8:
+		x = uintptr(*(*uint8)(p))
+	case reflect.Uint16:
+		x = uintptr(*(*uint16)(p))
+	case reflect.Uint32:
+		x = uintptr(*(*uint32)(p))
+	default:
+		// int, int64, uint, uint64, uintptr and unsafe.Pointer are as large as uintptr
+		x = *(*uintptr)(p)
+	}
+	c.ints[c.numInts] = x
+	c.numInts++
+}
+
+// call calls the C function with the arguments placed in c and stores its results in c.
+func (b *binder) call(c *bindCall) {
+	syscall := thePool.Get().(*syscall15Args)
+	*syscall = syscall15Args{
+		fn: b.cfn,
+		a1: c.ints[0], a2: c.ints[1], a3: c.ints[2], a4: c.ints[3],
+		a5: c.ints[4], a6: c.ints[5], a7: c.ints[6], a8: c.ints[7],
+		f1: c.floats[0], f2: c.floats[1], f3: c.floats[2], f4: c.floats[3],
+		f5: c.floats[4], f6: c.floats[5], f7: c.floats[6], f8: c.floats[7],
+	}
+	runtime_cgocall(syscall15XABI0, unsafe.Pointer(syscall))
+	c.r1, c.f1 = syscall.a1, syscall.f1
+	putSyscall(syscall)
+	runtime.KeepAlive(c.strs)
+}
+
+// result stores the result of the call c in the result that p points to.
+func (b *binder) result(c *bindCall, p unsafe.Pointer) {
+	switch b.ret {
+	case reflect.Float32:
+		*(*float32)(p) = math.Float32frombits(uint32(c.f1))
+	case reflect.Float64:
+		*(*float64)(p) = math.Float64frombits(uint64(c.f1))
+	case reflect.String:
+		*(*string)(p) = strings.GoString(c.r1)
+	case reflect.Bool:
+		*(*bool)(p) = byte(c.r1) != 0
+	case reflect.Int8, reflect.Uint8:
+		*(*uint8)(p) = uint8(c.r1)
+	case reflect.Int16, reflect.Uint16:
+		*(*uint16)(p) = uint16(c.r1)
+	case reflect.Int32, reflect.Uint32:
+		*(*uint32)(p) = uint32(c.r1)
+	case reflect.UnsafePointer:
+		// We take the address and then dereference it to trick go vet from creating a possible misuse of unsafe.Pointer
+		*(*unsafe.Pointer)(p) = *(*unsafe.Pointer)(unsafe.Pointer(&c.r1))
+	default:
+		*(*uintptr)(p) = c.r1
+	}
+}
+
+// Bind0 returns a function that calls the C function cfn with no arguments and returns its result.
+// See Bind2 for details.
+func Bind0[R BindType](cfn uintptr) func() R {
+	bd := newBinder(cfn, typeFor[R]())
+	if bd == nil {
+		var fn func() R
+		RegisterFunc(&fn, cfn)
+		return fn
+	}
+	return func() (r R) {
+		var call bindCall
+		bd.call(&call)
+		bd.result(&call, unsafe.Pointer(&r))
+		return r
+	}
+}
+
+// Bind1 returns a function that calls the C function cfn with one argument and returns its result.
+// See Bind2 for details.
+func Bind1[A, R BindType](cfn uintptr) func(A) R {
+	bd := newBinder(cfn, typeFor[R](), typeFor[A]())
+	if bd == nil {
+		var fn func(A) R
+		RegisterFunc(&fn, cfn)
+		return fn
+	}
+	return func(a A) (r R) {
+		var call bindCall
+		bd.arg(&call, 0, unsafe.Pointer(&a))
+		bd.call(&call)
+		bd.result(&call, unsafe.Pointer(&r))
+		return r
+	}
+}
+
+// Bind2 returns a function that calls the C function cfn with 2 arguments and returns its result.
+// Unlike RegisterFunc it doesn't use reflection so calling the function doesn't allocate unless
+// a string argument isn't null-terminated or the result is a string. It panics if cfn is nil.
+// On platforms without the specialized path it falls back to RegisterFunc.
+//
+//	ldexp := purego.Bind2[float64, int32, float64](ldexpSym)
+//	x := ldexp(1.5, 3) // 12
+func Bind2[A, B, R BindType](cfn uintptr) func(A, B) R {
+	bd := newBinder(cfn, typeFor[R](), typeFor[A](), typeFor[B]())
+	if bd == nil {
+		var fn func(A, B) R
+		RegisterFunc(&fn, cfn)
+		return fn
+	}
+	return func(a A, b B) (r R) {
+		var call bindCall
+		bd.arg(&call, 0, unsafe.Pointer(&a))
+		bd.arg(&call, 1, unsafe.Pointer(&b))
+		bd.call(&call)
+		bd.result(&call, unsafe.Pointer(&r))
+		return r
+	}
+}
+
+// Bind3 returns a function that calls the C function cfn with 3 arguments and returns its result.
+// See Bind2 for details.
+func Bind3[A, B, C, R BindType](cfn uintptr) func(A, B, C) R {
+	bd := newBinder(cfn, typeFor[R](), typeFor[A](), typeFor[B](), typeFor[C]())
+	if bd == nil {
+		var fn func(A, B, C) R
+		RegisterFunc(&fn, cfn)
+		return fn
+	}
+	return func(a A, b B, c C) (r R) {
+		var call bindCall
+		bd.arg(&call, 0, unsafe.Pointer(&a))
+		bd.arg(&call, 1, unsafe.Pointer(&b))
+		bd.arg(&call, 2, unsafe.Pointer(&c))
+		bd.call(&call)
+		bd.result(&call, unsafe.Pointer(&r))
+		return r
+	}
+}
+
+// Bind4 returns a function that calls the C function cfn with 4 arguments and returns its result.
+// See Bind2 for details.
+func Bind4[A, B, C, D, R BindType](cfn uintptr) func(A, B, C, D) R {
+	bd := newBinder(cfn, typeFor[R](), typeFor[A](), typeFor[B](), typeFor[C](), typeFor[D]())
+	if bd == nil {
+		var fn func(A, B, C, D) R
+		RegisterFunc(&fn, cfn)
+		return fn
+	}
+	return func(a A, b B, c C, d D) (r R) {
+		var call bindCall
+		bd.arg(&call, 0, unsafe.Pointer(&a))
+		bd.arg(&call, 1, unsafe.Pointer(&b))
+		bd.arg(&call, 2, unsafe.Pointer(&c))
+		bd.arg(&call, 3, unsafe.Pointer(&d))
+		bd.call(&call)
+		bd.result(&call, unsafe.Pointer(&r))
+		return r
+	}
+}
+
+// Bind5 returns a function that calls the C function cfn with 5 arguments and returns its result.
+// See Bind2 for details.
+func Bind5[A, B, C, D, E, R BindType](cfn uintptr) func(A, B, C, D, E) R {
+	bd := newBinder(cfn, typeFor[R](), typeFor[A](), typeFor[B](), typeFor[C](), typeFor[D](), typeFor[E]())
+	if bd == nil {
+		var fn func(A, B, C, D, E) R
+		RegisterFunc(&fn, cfn)
+		return fn
+	}
+	return func(a A, b B, c C, d D, e E) (r R) {
+		var call bindCall
+		bd.arg(&call, 0, unsafe.Pointer(&a))
+		bd.arg(&call, 1, unsafe.Pointer(&b))
+		bd.arg(&call, 2, unsafe.Pointer(&c))
+		bd.arg(&call, 3, unsafe.Pointer(&d))
+		bd.arg(&call, 4, unsafe.Pointer(&e))
+		bd.call(&call)
+		bd.result(&call, unsafe.Pointer(&r))
+		return r
+	}
+}
+
+// Bind6 returns a function that calls the C function cfn with 6 arguments and returns its result.
+// See Bind2 for details.
+func Bind6[A, B, C, D, E, F, R BindType](cfn uintptr) func(A, B, C, D, E, F) R {
+	bd := newBinder(cfn, typeFor[R](), typeFor[A](), typeFor[B](), typeFor[C](), typeFor[D](), typeFor[E](), typeFor[F]())
+	if bd == nil {
+		var fn func(A, B, C, D, E, F) R
+		RegisterFunc(&fn, cfn)
+		return fn
+	}
+	return func(a A, b B, c C, d D, e E, f F) (r R) {
+		var call bindCall
+		bd.arg(&call, 0, unsafe.Pointer(&a))
+		bd.arg(&call, 1, unsafe.Pointer(&b))
+		bd.arg(&call, 2, unsafe.Pointer(&c))
+		bd.arg(&call, 3, unsafe.Pointer(&d))
+		bd.arg(&call, 4, unsafe.Pointer(&e))
+		bd.arg(&call, 5, unsafe.Pointer(&f))
+		bd.call(&call)
+		bd.result(&call, unsafe.Pointer(&r))
+		return r
+	}
+}
+
+// Bind7 returns a function that calls the C function cfn with 7 arguments and returns its result.
+// See Bind2 for details.
+func Bind7[A, B, C, D, E, F, G, R BindType](cfn uintptr) func(A, B, C, D, E, F, G) R {
+	bd := newBinder(cfn, typeFor[R](), typeFor[A](), typeFor[B](), typeFor[C](), typeFor[D](), typeFor[E](), typeFor[F](), typeFor[G]())
+	if bd == nil {
+		var fn func(A, B, C, D, E, F, G) R
+		RegisterFunc(&fn, cfn)
+		return fn
+	}
+	return func(a A, b B, c C, d D, e E, f F, g G) (r R) {
+		var call bindCall
+		bd.arg(&call, 0, unsafe.Pointer(&a))
+		bd.arg(&call, 1, unsafe.Pointer(&b))
+		bd.arg(&call, 2, unsafe.Pointer(&c))
+		bd.arg(&call, 3, unsafe.Pointer(&d))
+		bd.arg(&call, 4, unsafe.Pointer(&e))
+		bd.arg(&call, 5, unsafe.Pointer(&f))
+		bd.arg(&call, 6, unsafe.Pointer(&g))
+		bd.call(&call)
+		bd.result(&call, unsafe.Pointer(&r))
+		return r
+	}
+}
+
+// Bind8 returns a function that calls the C function cfn with 8 arguments and returns its result.
+// See Bind2 for details.
+func Bind8[A, B, C, D, E, F, G, H, R BindType](cfn uintptr) func(A, B, C, D, E, F, G, H) R {
+	bd := newBinder(cfn, typeFor[R](), typeFor[A](), typeFor[B](), typeFor[C](), typeFor[D](), typeFor[E](), typeFor[F](), typeFor[G](), typeFor[H]())
+	if bd == nil {
+		var fn func(A, B, C, D, E, F, G, H) R
+		RegisterFunc(&fn, cfn)
+		return fn
+	}
+	return func(a A, b B, c C, d D, e E, f F, g G, h H) (r R) {
+		var call bindCall
+		bd.arg(&call, 0, unsafe.Pointer(&a))
+		bd.arg(&call, 1, unsafe.Pointer(&b))
+		bd.arg(&call, 2, unsafe.Pointer(&c))
+		bd.arg(&call, 3, unsafe.Pointer(&d))
+		bd.arg(&call, 4, unsafe.Pointer(&e))
+		bd.arg(&call, 5, unsafe.Pointer(&f))
+		bd.arg(&call, 6, unsafe.Pointer(&g))
+		bd.arg(&call, 7, unsafe.Pointer(&h))
+		bd.call(&call)
+		bd.result(&call, unsafe.Pointer(&r))
+		return r
+	}
+}
+
+// BindVoid0 is like Bind0 for a C function that returns void.
+func BindVoid0(cfn uintptr) func() {
+	bd := newBinder(cfn, nil)
+	if bd == nil {
+		var fn func()
+		RegisterFunc(&fn, cfn)
+		return fn
+	}
+	return func() {
+		var call bindCall
+		bd.call(&call)
+	}
+}
+
+// BindVoid1 is like Bind1 for a C function that returns void.
+func BindVoid1[A BindType](cfn uintptr) func(A) {
+	bd := newBinder(cfn, nil, typeFor[A]())
+	if bd == nil {
+		var fn func(A)
+		RegisterFunc(&fn, cfn)
+		return fn
+	}
+	return func(a A) {
+		var call bindCall
+		bd.arg(&call, 0, unsafe.Pointer(&a))
+		bd.call(&call)
+	}
+}
+
+// BindVoid2 is like Bind2 for a C function that returns void.
+func BindVoid2[A, B BindType](cfn uintptr) func(A, B) {
+	bd := newBinder(cfn, nil, typeFor[A](), typeFor[B]())
+	if bd == nil {
+		var fn func(A, B)
+		RegisterFunc(&fn, cfn)
+		return fn
+	}
+	return func(a A, b B) {
+		var call bindCall
+		bd.arg(&call, 0, unsafe.Pointer(&a))
+		bd.arg(&call, 1, unsafe.Pointer(&b))
+		bd.call(&call)
+	}
+}
+
+// BindVoid3 is like Bind3 for a C function that returns void.
+func BindVoid3[A, B, C BindType](cfn uintptr) func(A, B, C) {
+	bd := newBinder(cfn, nil, typeFor[A](), typeFor[B](), typeFor[C]())
+	if bd == nil {
+		var fn func(A, B, C)
+		RegisterFunc(&fn, cfn)
+		return fn
+	}
+	return func(a A, b B, c C) {
+		var call bindCall
+		bd.arg(&call, 0, unsafe.Pointer(&a))
+		bd.arg(&call, 1, unsafe.Pointer(&b))
+		bd.arg(&call, 2, unsafe.Pointer(&c))
+		bd.call(&call)
+	}
+}
+
+// BindVoid4 is like Bind4 for a C function that returns void.
+func BindVoid4[A, B, C, D BindType](cfn uintptr) func(A, B, C, D) {
+	bd := newBinder(cfn, nil, typeFor[A](), typeFor[B](), typeFor[C](), typeFor[D]())
+	if bd == nil {
+		var fn func(A, B, C, D)
+		RegisterFunc(&fn, cfn)
+		return fn
+	}
+	return func(a A, b B, c C, d D) {
+		var call bindCall
+		bd.arg(&call, 0, unsafe.Pointer(&a))
+		bd.arg(&call, 1, unsafe.Pointer(&b))
+		bd.arg(&call, 2, unsafe.Pointer(&c))
+		bd.arg(&call, 3, unsafe.Pointer(&d))
+		bd.call(&call)
+	}
+}
+
+// BindVoid5 is like Bind5 for a C function that returns void.
+func BindVoid5[A, B, C, D, E BindType](cfn uintptr) func(A, B, C, D, E) {
+	bd := newBinder(cfn, nil, typeFor[A](), typeFor[B](), typeFor[C](), typeFor[D](), typeFor[E]())
+	if bd == nil {
+		var fn func(A, B, C, D, E)
+		RegisterFunc(&fn, cfn)
+		return fn
+	}
+	return func(a A, b B, c C, d D, e E) {
+		var call bindCall
+		bd.arg(&call, 0, unsafe.Pointer(&a))
+		bd.arg(&call, 1, unsafe.Pointer(&b))
+		bd.arg(&call, 2, unsafe.Pointer(&c))
+		bd.arg(&call, 3, unsafe.Pointer(&d))
+		bd.arg(&call, 4, unsafe.Pointer(&e))
+		bd.call(&call)
+	}
+}
+
+// BindVoid6 is like Bind6 for a C function that returns void.
+func BindVoid6[A, B, C, D, E, F BindType](cfn uintptr) func(A, B, C, D, E, F) {
+	bd := newBinder(cfn, nil, typeFor[A](), typeFor[B](), typeFor[C](), typeFor[D](), typeFor[E](), typeFor[F]())
+	if bd == nil {
+		var fn func(A, B, C, D, E, F)
+		RegisterFunc(&fn, cfn)
+		return fn
+	}
+	return func(a A, b B, c C, d D, e E, f F) {
+		var call bindCall
+		bd.arg(&call, 0, unsafe.Pointer(&a))
+		bd.arg(&call, 1, unsafe.Pointer(&b))
+		bd.arg(&call, 2, unsafe.Pointer(&c))
+		bd.arg(&call, 3, unsafe.Pointer(&d))
+		bd.arg(&call, 4, unsafe.Pointer(&e))
+		bd.arg(&call, 5, unsafe.Pointer(&f))
+		bd.call(&call)
+	}
+}
+
+// BindVoid7 is like Bind7 for a C function that returns void.
+func BindVoid7[A, B, C, D, E, F, G BindType](cfn uintptr) func(A, B, C, D, E, F, G) {
+	bd := newBinder(cfn, nil, typeFor[A](), typeFor[B](), typeFor[C](), typeFor[D](), typeFor[E](), typeFor[F](), typeFor[G]())
+	if bd == nil {
+		var fn func(A, B, C, D, E, F, G)
+		RegisterFunc(&fn, cfn)
+		return fn
+	}
+	return func(a A, b B, c C, d D, e E, f F, g G) {
+		var call bindCall
+		bd.arg(&call, 0, unsafe.Pointer(&a))
+		bd.arg(&call, 1, unsafe.Pointer(&b))
+		bd.arg(&call, 2, unsafe.Pointer(&c))
+		bd.arg(&call, 3, unsafe.Pointer(&d))
+		bd.arg(&call, 4, unsafe.Pointer(&e))
+		bd.arg(&call, 5, unsafe.Pointer(&f))
+		bd.arg(&call, 6, unsafe.Pointer(&g))
+		bd.call(&call)
+	}
+}
+
+// BindVoid8 is like Bind8 for a C function that returns void.
+func BindVoid8[A, B, C, D, E, F, G, H BindType](cfn uintptr) func(A, B, C, D, E, F, G, H) {
+	bd := newBinder(cfn, nil, typeFor[A](), typeFor[B](), typeFor[C](), typeFor[D](), typeFor[E](), typeFor[F](), typeFor[G](), typeFor[H]())
+	if bd == nil {
+		var fn func(A, B, C, D, E, F, G, H)
+		RegisterFunc(&fn, cfn)
+		return fn
+	}
+	return func(a A, b B, c C, d D, e E, f F, g G, h H) {
+		var call bindCall
+		bd.arg(&call, 0, unsafe.Pointer(&a))
+		bd.arg(&call, 1, unsafe.Pointer(&b))
+		bd.arg(&call, 2, unsafe.Pointer(&c))
+		bd.arg(&call, 3, unsafe.Pointer(&d))
+		bd.arg(&call, 4, unsafe.Pointer(&e))
+		bd.arg(&call, 5, unsafe.Pointer(&f))
+		bd.arg(&call, 6, unsafe.Pointer(&g))
+		bd.arg(&call, 7, unsafe.Pointer(&h))
+		bd.call(&call)
+	}
+}
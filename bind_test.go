@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+package purego_test
+
+import (
+	"runtime"
+	"testing"
+	"unsafe"
+
+	"github.com/ebitengine/purego"
+	"github.com/ebitengine/purego/internal/load"
+)
+
+func TestBind(t *testing.T) {
+	if runtime.GOARCH != "arm64" && runtime.GOARCH != "amd64" && runtime.GOARCH != "loong64" {
+		t.Skip("Platform doesn't support Floats")
+	}
+	library, err := getSystemLibrary()
+	if err != nil {
+		t.Fatalf("couldn't get system library: %s", err)
+	}
+	libc, err := load.OpenLibrary(library)
+	if err != nil {
+		t.Fatalf("failed to dlopen: %s", err)
+	}
+	sym := func(name string) uintptr {
+		t.Helper()
+		fn, err := load.OpenSymbol(libc, name)
+		if err != nil {
+			t.Fatalf("failed to find %s: %s", name, err)
+		}
+		return fn
+	}
+
+	ldexp := purego.Bind2[float64, int32, float64](sym("ldexp"))
+	if got := ldexp(1.5, 3); got != 12 {
+		t.Errorf("ldexp(1.5, 3) returned %v wanted 12", got)
+	}
+	ldexpf := purego.Bind2[float32, int32, float32](sym("ldexpf"))
+	if got := ldexpf(-1.5, -1); got != -0.75 {
+		t.Errorf("ldexpf(-1.5, -1) returned %v wanted -0.75", got)
+	}
+	type myInt int32
+	abs := purego.Bind1[myInt, myInt](sym("abs"))
+	if got := abs(-7); got != 7 {
+		t.Errorf("abs(-7) returned %d wanted 7", got)
+	}
+	strlen := purego.Bind1[string, uintptr](sym("strlen"))
+	if got := strlen("hello"); got != 5 {
+		t.Errorf(`strlen("hello") returned %d wanted 5`, got)
+	}
+	strchr := purego.Bind2[string, int32, string](sym("strchr"))
+	if got := strchr("hello, world", ','); got != ", world" {
+		t.Errorf(`strchr returned %q wanted ", world"`, got)
+	}
+	buf := make([]byte, 4)
+	memset := purego.BindVoid3[unsafe.Pointer, int32, uintptr](sym("memset"))
+	memset(unsafe.Pointer(&buf[0]), 'x', 3)
+	if got := string(buf); got != "xxx\x00" {
+		t.Errorf("memset wrote %q wanted %q", got, "xxx\x00")
+	}
+
+	if runtime.GOOS == "windows" {
+		return // Windows falls back to RegisterFunc
+	}
+	if allocs := testing.AllocsPerRun(100, func() {
+		ldexp(1.5, 3)
+		abs(-7)
+		strlen("hello\x00")
+	}); allocs != 0 {
+		t.Errorf("calling bound functions allocated %v times", allocs)
+	}
+}
@@ -0,0 +1,32 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build darwin || freebsd || linux || netbsd || windows
+
+package purego
+
+import "sync"
+
+// Callback is a Go function that can be called from C. It is created by NewReleasableCallback
+// and NewCallback0 to NewCallback8.
+type Callback struct {
+	ptr   uintptr
+	index int
+	once  sync.Once
+}
+
+// Ptr returns the C function pointer of c. It must not be called by C after c is released.
+func (c *Callback) Ptr() uintptr {
+	return c.ptr
+}
+
+// Release makes the slot of c available to new callbacks. The function pointer of c may then call
+// another Go function, so C code must not hold on to it anymore. Calling Release more than once does nothing.
+// It also does nothing on Windows where callbacks are never released.
+func (c *Callback) Release() {
+	c.once.Do(func() {
+		if c.index >= 0 {
+			releaseCallback(c.index)
+		}
+	})
+}
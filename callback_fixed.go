@@ -0,0 +1,15 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build (darwin || freebsd || (linux && (amd64 || arm64 || loong64)) || netbsd) && !((freebsd || linux) && (amd64 || arm64))
+
+package purego
+
+// callbacksGrow reports whether callbacks past maxCB are created with newTrampoline.
+// Mapping executable memory isn't allowed by default on Darwin and NetBSD and there are no
+// trampolines for the other architectures so only the callbackasm table is used.
+const callbacksGrow = false
+
+func newTrampoline(index int) uintptr {
+	panic("purego: newTrampoline is not supported on this platform")
+}
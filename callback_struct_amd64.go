@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build darwin || freebsd || linux || netbsd
+
+package purego
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// structReturnedInMemory reports whether a struct result of type t, a type returned by cStructType,
+// is written to memory whose address the caller passes as a hidden first argument.
+func structReturnedInMemory(t reflect.Type) bool {
+	return t.Size() > 16 || hasUnalignedFields(t)
+}
+
+// callbackResultPointer returns the address for a struct result that is returned in memory.
+// On amd64 it is passed in the first integer register.
+func callbackResultPointer(a *callbackArgs, f *callbackFrame) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&f.frame[f.nextInt()]))
+}
+
+// callbackStruct returns the struct argument of type t, a type returned by cStructType, from f.
+// It reads the struct from where addStruct places it.
+func callbackStruct(t reflect.Type, f *callbackFrame) reflect.Value {
+	v := reflect.New(t).Elem()
+	if t.Size() == 0 {
+		return v
+	}
+	if postMerger(t) || hasUnalignedFields(t) {
+		return f.readStack(t)
+	}
+	classes, n := classifyEightbytes(t)
+	var needInts, needFloats int
+	for i := 0; i < n; i++ {
+		if classes[i] == _SSE {
+			needFloats++
+		} else {
+			needInts++
+		}
+	}
+	if f.ints+needInts > numOfIntegerRegisters() || f.floats+needFloats > numOfFloatRegisters {
+		return f.readStack(t)
+	}
+	i := 0
+	readStruct8ByteChunks(v.Addr().UnsafePointer(), t.Size(), func() uintptr {
+		var pos int
+		if classes[i] == _SSE {
+			pos = f.nextFloat()
+		} else {
+			pos = f.nextInt()
+		}
+		i++
+		return f.frame[pos]
+	})
+	return v
+}
+
+// setCallbackStructResult sets the result registers of a to the struct v which is returned in registers.
+// Each eightbyte is returned in the next free register of its class like getStruct reads it.
+func setCallbackStructResult(a *callbackArgs, v reflect.Value) {
+	classes, _ := classifyEightbytes(v.Type())
+	ints := [2]*uintptr{&a.result, &a.result2}
+	var i, numInts, numFloats int
+	copyStruct8ByteChunks(v, func(chunk uintptr) {
+		if classes[i] == _SSE {
+			a.floatResult[numFloats] = chunk
+			numFloats++
+		} else {
+			*ints[numInts] = chunk
+			numInts++
+		}
+		i++
+	})
+}
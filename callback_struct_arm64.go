@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build darwin || freebsd || linux || netbsd
+
+package purego
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// structReturnedInMemory reports whether a struct result of type t, a type returned by cStructType,
+// is written to memory whose address the caller passes in X8.
+func structReturnedInMemory(t reflect.Type) bool {
+	_, _, hfa := hfaMembers(t)
+	return !hfa && t.Size() > maxRegAllocStructSize
+}
+
+// callbackResultPointer returns the address for a struct result that is returned in memory.
+// On arm64 it is passed in X8 which callbackasm1 saves in a.
+func callbackResultPointer(a *callbackArgs, f *callbackFrame) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&a.arm64_r8))
+}
+
+// callbackStruct returns the struct argument of type t, a type returned by cStructType, from f.
+// It follows the rules of addStructAAPCS64. Like the other callback arguments the stack is read
+// in 8-byte slots on Darwin too.
+func callbackStruct(t reflect.Type, f *callbackFrame) reflect.Value {
+	v := reflect.New(t).Elem()
+	size := t.Size()
+	if size == 0 {
+		return v
+	}
+	ptr := v.Addr().UnsafePointer()
+	if member, n, ok := hfaMembers(t); ok {
+		if f.floats+n > numOfFloatRegisters {
+			f.floats = numOfFloatRegisters
+			return f.readStack(t)
+		}
+		memberSize := size / uintptr(n)
+		for i := 0; i < n; i++ {
+			bits := f.frame[f.nextFloat()]
+			if member == reflect.Float32 {
+				*(*uint32)(unsafe.Add(ptr, uintptr(i)*memberSize)) = uint32(bits)
+			} else {
+				*(*uint64)(unsafe.Add(ptr, uintptr(i)*memberSize)) = uint64(bits)
+			}
+		}
+		return v
+	}
+	if size > maxRegAllocStructSize {
+		// the caller passes a pointer to a copy
+		v.Set(reflect.NewAt(t, *(*unsafe.Pointer)(unsafe.Pointer(&f.frame[f.nextInt()]))).Elem())
+		return v
+	}
+	if cStructAlign(t) > 8 && f.ints%2 != 0 && f.ints < numOfIntegerRegisters() {
+		f.ints++
+	}
+	if f.ints+int(roundUpTo8(size)/align8ByteSize) > numOfIntegerRegisters() {
+		f.ints = numOfIntegerRegisters()
+		return f.readStack(t)
+	}
+	readStruct8ByteChunks(ptr, size, func() uintptr { return f.frame[f.nextInt()] })
+	return v
+}
+
+// setCallbackStructResult sets the result registers of a to the struct v which is returned in registers.
+// An HFA is returned with one member per floating-point register and any other struct in X0 and X1.
+func setCallbackStructResult(a *callbackArgs, v reflect.Value) {
+	ptr, size := structMemory(v)
+	if size == 0 {
+		return
+	}
+	if member, n, ok := hfaMembers(v.Type()); ok {
+		memberSize := size / uintptr(n)
+		for i := 0; i < n; i++ {
+			if member == reflect.Float32 {
+				a.floatResult[i] = uintptr(*(*uint32)(unsafe.Add(ptr, uintptr(i)*memberSize)))
+			} else {
+				a.floatResult[i] = uintptr(*(*uint64)(unsafe.Add(ptr, uintptr(i)*memberSize)))
+			}
+		}
+		return
+	}
+	regs := [2]*uintptr{&a.result, &a.result2}
+	i := 0
+	copyStruct8ByteChunks(ptr, size, func(chunk uintptr) {
+		*regs[i] = chunk
+		i++
+	})
+}
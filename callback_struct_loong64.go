@@ -0,0 +1,29 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build linux
+
+package purego
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// Struct arguments and results of callbacks are rejected by checkCallbackStruct on loong64.
+
+func structReturnedInMemory(t reflect.Type) bool {
+	panic("purego: structReturnedInMemory should not be called on loong64")
+}
+
+func callbackResultPointer(a *callbackArgs, f *callbackFrame) unsafe.Pointer {
+	panic("purego: callbackResultPointer should not be called on loong64")
+}
+
+func callbackStruct(t reflect.Type, f *callbackFrame) reflect.Value {
+	panic("purego: callbackStruct should not be called on loong64")
+}
+
+func setCallbackStructResult(a *callbackArgs, v reflect.Value) {
+	panic("purego: setCallbackStructResult should not be called on loong64")
+}
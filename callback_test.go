@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 	"unsafe"
 
@@ -173,3 +174,338 @@ func ExampleNewCallback_cdecl() {
 
 	// Output: 83
 }
+
+func TestReleasableCallback(t *testing.T) {
+	used, _ := purego.CallbackSlots()
+	cb := purego.NewReleasableCallback(func(a int) int { return a + 1 })
+	if got, _ := purego.CallbackSlots(); got != used+1 {
+		t.Errorf("CallbackSlots returned %d used slots but wanted %d", got, used+1)
+	}
+	var call func(int) int
+	purego.RegisterFunc(&call, cb.Ptr())
+	if got := call(1); got != 2 {
+		t.Errorf("callback returned %d but wanted %d", got, 2)
+	}
+	cb.Release()
+	cb.Release()
+	if got, _ := purego.CallbackSlots(); got != used {
+		t.Errorf("CallbackSlots returned %d used slots after Release but wanted %d", got, used)
+	}
+
+	reused := purego.NewReleasableCallback(func(a int) int { return a * 10 })
+	defer reused.Release()
+	if reused.Ptr() != cb.Ptr() {
+		t.Errorf("a released slot wasn't reused")
+	}
+	if got := call(3); got != 30 {
+		t.Errorf("reused callback returned %d but wanted %d", got, 30)
+	}
+}
+
+func TestCallbackGrowth(t *testing.T) {
+	used, remaining := purego.CallbackSlots()
+	if remaining != -1 {
+		t.Skip("callbacks don't grow on this platform")
+	}
+	// go past the callbackasm table and fill more than one page of trampolines
+	n := 2000 - used + 300
+	cbs := make([]*purego.Callback, n)
+	for i := range cbs {
+		i := i
+		cbs[i] = purego.NewReleasableCallback(func(a int) int { return a + i })
+	}
+	defer func() {
+		for _, cb := range cbs {
+			cb.Release()
+		}
+	}()
+	for _, i := range []int{0, n - 301, n - 300, n - 1} {
+		var call func(int) int
+		purego.RegisterFunc(&call, cbs[i].Ptr())
+		if got := call(1); got != 1+i {
+			t.Errorf("callback %d returned %d but wanted %d", i, got, 1+i)
+		}
+	}
+}
+
+func TestNewCallbackFloatResult(t *testing.T) {
+	libFileName := filepath.Join(t.TempDir(), "libcbtest.so")
+	if err := buildSharedLib("CC", libFileName, filepath.Join("testdata", "libcbtest", "callback_test.c")); err != nil {
+		t.Fatal(err)
+	}
+	lib, err := purego.Dlopen(libFileName, purego.RTLD_NOW|purego.RTLD_GLOBAL)
+	if err != nil {
+		t.Fatalf("Dlopen(%q) failed: %v", libFileName, err)
+	}
+
+	var sumEasing func(cb uintptr, steps int32) float64
+	purego.RegisterLibFunc(&sumEasing, lib, "sumEasing")
+	quad := purego.NewCallback(func(t float64) float64 { return t * t })
+	if got, want := sumEasing(quad, 4), 1.875; got != want {
+		t.Errorf("sumEasing returned %f but wanted %f", got, want)
+	}
+
+	var sumSamples func(cb uintptr, n int32) float32
+	purego.RegisterLibFunc(&sumSamples, lib, "sumSamples")
+	half := purego.NewCallback(func(i int32) float32 { return float32(i) + 0.5 })
+	if got, want := sumSamples(half, 4), float32(8); got != want {
+		t.Errorf("sumSamples returned %f but wanted %f", got, want)
+	}
+}
+
+func TestNewCallbackStruct(t *testing.T) {
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		t.Skip("struct arguments and results of callbacks are only supported on amd64 and arm64")
+	}
+	libFileName := filepath.Join(t.TempDir(), "libcbtest.so")
+	if err := buildSharedLib("CC", libFileName, filepath.Join("testdata", "libcbtest", "callback_test.c")); err != nil {
+		t.Fatal(err)
+	}
+	lib, err := purego.Dlopen(libFileName, purego.RTLD_NOW|purego.RTLD_GLOBAL)
+	if err != nil {
+		t.Fatalf("Dlopen(%q) failed: %v", libFileName, err)
+	}
+
+	type size struct{ Width, Height int32 }
+	type point struct{ X, Y float64 }
+	type mixed struct {
+		F float32
+		L int64
+	}
+	type big struct{ A, B, C, D int64 }
+
+	var callResize func(cb uintptr, width, height int32) int32
+	purego.RegisterLibFunc(&callResize, lib, "callResize")
+	area := purego.NewCallback(func(s size) int32 { return s.Width * s.Height })
+	if got := callResize(area, 3, 4); got != 12 {
+		t.Errorf("callResize returned %d but wanted %d", got, 12)
+	}
+
+	var callResizeOnStack func(cb uintptr, width, height int32) int32
+	purego.RegisterLibFunc(&callResizeOnStack, lib, "callResizeOnStack")
+	areaOnStack := purego.NewCallback(func(a1, a2, a3, a4, a5, a6, a7, a8 int64, s size) int32 {
+		return int32(a1+a2+a3+a4+a5+a6+a7+a8) + s.Width*s.Height
+	})
+	if got := callResizeOnStack(areaOnStack, 3, 4); got != 48 {
+		t.Errorf("callResizeOnStack returned %d but wanted %d", got, 48)
+	}
+
+	var callPoint func(cb uintptr, x, y float64) float64
+	purego.RegisterLibFunc(&callPoint, lib, "callPoint")
+	scale := purego.NewCallback(func(p point, f float64) point { return point{p.X * f, p.Y * f} })
+	if got := callPoint(scale, 1.5, 2); got != 34 {
+		t.Errorf("callPoint returned %f but wanted %f", got, 34.0)
+	}
+
+	var callMixed func(cb uintptr, f float32, l int64) int64
+	purego.RegisterLibFunc(&callMixed, lib, "callMixed")
+	double := purego.NewCallback(func(m mixed) mixed { return mixed{m.F * 2, m.L * 2} })
+	if got := callMixed(double, 1.25, 7); got != 264 {
+		t.Errorf("callMixed returned %d but wanted %d", got, 264)
+	}
+
+	var callBig func(cb uintptr, n int64) int64
+	purego.RegisterLibFunc(&callBig, lib, "callBig")
+	sub := purego.NewCallback(func(b big, n int64) big { return big{b.A - n, b.B - n, b.C - n, b.D - n} })
+	if got, want := callBig(sub, 11), int64(1+2*10+3*100+4*1000); got != want {
+		t.Errorf("callBig returned %d but wanted %d", got, want)
+	}
+
+	type quad struct {
+		Lo struct{ V [2]float64 }
+		Hi [2]float64
+	}
+	var callQuad func(cb uintptr, x float64) float64
+	purego.RegisterLibFunc(&callQuad, lib, "callQuad")
+	spread := purego.NewCallback(func(x float64) quad {
+		var q quad
+		q.Lo.V = [2]float64{x, x + 1}
+		q.Hi = [2]float64{x + 2, x + 3}
+		return q
+	})
+	if got, want := callQuad(spread, 1), float64(1+2*10+3*100+4*1000); got != want {
+		t.Errorf("callQuad returned %f but wanted %f", got, want)
+	}
+}
+
+func TestNewCallbackFloatArguments(t *testing.T) {
+	libFileName := filepath.Join(t.TempDir(), "libcbtest.so")
+	if err := buildSharedLib("CC", libFileName, filepath.Join("testdata", "libcbtest", "callback_test.c")); err != nil {
+		t.Fatal(err)
+	}
+	lib, err := purego.Dlopen(libFileName, purego.RTLD_NOW|purego.RTLD_GLOBAL)
+	if err != nil {
+		t.Fatalf("Dlopen(%q) failed: %v", libFileName, err)
+	}
+
+	var callDoubles func(cb uintptr, x float64) float64
+	purego.RegisterLibFunc(&callDoubles, lib, "callDoubles")
+	var args [9]float64
+	weigh := purego.NewCallback(func(f1, f2, f3, f4 float64, n int64, f5, f6, f7, f8 float64) float64 {
+		args = [9]float64{f1, f2, f3, f4, float64(n), f5, f6, f7, f8}
+		return f1 + f2*2 + f3*3 + f4*4 + float64(n) + f5*5 + f6*6 + f7*7 + f8*8
+	})
+	for i := 0; i < 10; i++ {
+		// 0.5*36 + 1*2 + 2*3 + 3*4 + 100 + 4*5 + 5*6 + 6*7 + 7*8
+		if got, want := callDoubles(weigh, 0.5), 286.0; got != want {
+			t.Fatalf("callDoubles returned %f but wanted %f", got, want)
+		}
+		if want := [9]float64{0.5, 1.5, 2.5, 3.5, 100, 4.5, 5.5, 6.5, 7.5}; args != want {
+			t.Fatalf("callback got %v but wanted %v", args, want)
+		}
+	}
+
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		t.Skip("struct arguments of callbacks are only supported on amd64 and arm64")
+	}
+	type point struct{ X, Y float64 }
+	var callFloats func(cb uintptr, x, y float64) float64
+	purego.RegisterLibFunc(&callFloats, lib, "callFloats")
+	var got [5]float64
+	cb := purego.NewCallback(func(a float64, p point, b float64, c float32) float64 {
+		got = [5]float64{a, p.X, p.Y, b, float64(c)}
+		return a + p.X + p.Y + b + float64(c)
+	})
+	for i := 0; i < 10; i++ {
+		if sum := callFloats(cb, 1.5, -3); sum != 7.25 {
+			t.Fatalf("callFloats returned %f but wanted %f", sum, 7.25)
+		}
+		if want := [5]float64{0.5, 1.5, -3, 0.25, 8}; got != want {
+			t.Fatalf("callback got %v but wanted %v", got, want)
+		}
+	}
+}
+
+func TestNewCallbackStringAndFunc(t *testing.T) {
+	libFileName := filepath.Join(t.TempDir(), "libcbtest.so")
+	if err := buildSharedLib("CC", libFileName, filepath.Join("testdata", "libcbtest", "callback_test.c")); err != nil {
+		t.Fatal(err)
+	}
+	lib, err := purego.Dlopen(libFileName, purego.RTLD_NOW|purego.RTLD_GLOBAL)
+	if err != nil {
+		t.Fatalf("Dlopen(%q) failed: %v", libFileName, err)
+	}
+
+	var messages []string
+	var callLogger func(cb uintptr) int32
+	purego.RegisterLibFunc(&callLogger, lib, "callLogger")
+	logger := purego.NewCallback(func(msg string, level int32) int32 {
+		messages = append(messages, msg)
+		return level
+	})
+	if got := callLogger(logger); got != 3 {
+		t.Errorf("callLogger returned %d but wanted %d", got, 3)
+	}
+	if len(messages) != 2 || messages[0] != "disk full" || messages[1] != "" {
+		t.Errorf("logger received %q but wanted %q", messages, []string{"disk full", ""})
+	}
+
+	var callWithOp func(cb uintptr, withOp bool) int64
+	purego.RegisterLibFunc(&callWithOp, lib, "callWithOp")
+	apply := purego.NewCallback(func(op func(a, b int64) int64, n int64) int64 {
+		if op == nil {
+			return -1
+		}
+		return op(n, 7)
+	})
+	if got := callWithOp(apply, true); got != 42 {
+		t.Errorf("callWithOp returned %d but wanted %d", got, 42)
+	}
+	if got := callWithOp(apply, false); got != -1 {
+		t.Errorf("callWithOp with NULL returned %d but wanted %d", got, -1)
+	}
+	// the same C function pointer is passed as the same Go function
+	var ops []unsafe.Pointer
+	record := purego.NewCallback(func(op func(a, b int64) int64, n int64) int64 {
+		ops = append(ops, *(*unsafe.Pointer)(unsafe.Pointer(&op)))
+		return op(n, 7)
+	})
+	callWithOp(record, true)
+	callWithOp(record, true)
+	if len(ops) != 2 || ops[0] != ops[1] {
+		t.Errorf("callWithOp passed different functions %v for the same function pointer", ops)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("a callback with an unsupported func argument didn't panic")
+		}
+	}()
+	purego.NewCallback(func(op func(map[int]int)) {})
+}
+
+func TestNewCallbackTyped(t *testing.T) {
+	libFileName := filepath.Join(t.TempDir(), "libcbtest.so")
+	if err := buildSharedLib("CC", libFileName, filepath.Join("testdata", "libcbtest", "callback_test.c")); err != nil {
+		t.Fatal(err)
+	}
+	lib, err := purego.Dlopen(libFileName, purego.RTLD_NOW|purego.RTLD_GLOBAL)
+	if err != nil {
+		t.Fatalf("Dlopen(%q) failed: %v", libFileName, err)
+	}
+
+	var sumEasing func(cb uintptr, steps int32) float64
+	purego.RegisterLibFunc(&sumEasing, lib, "sumEasing")
+	quad := purego.NewCallback1(func(t float64) float64 { return t * t })
+	defer quad.Release()
+	if got, want := sumEasing(quad.Ptr(), 4), 1.875; got != want {
+		t.Errorf("sumEasing returned %f but wanted %f", got, want)
+	}
+
+	var sumSamples func(cb uintptr, n int32) float32
+	purego.RegisterLibFunc(&sumSamples, lib, "sumSamples")
+	half := purego.NewCallback1(func(i int32) float32 { return float32(i) + 0.5 })
+	defer half.Release()
+	if got, want := sumSamples(half.Ptr(), 4), float32(8); got != want {
+		t.Errorf("sumSamples returned %f but wanted %f", got, want)
+	}
+
+	var zero func() int64
+	purego.RegisterFunc(&zero, purego.NewCallback0(func() int64 { return -7 }).Ptr())
+	if got := zero(); got != -7 {
+		t.Errorf("NewCallback0 returned %d but wanted %d", got, -7)
+	}
+
+	var sub func(a, b int64) int64
+	purego.RegisterFunc(&sub, purego.NewCallback2(func(a, b int64) int64 { return a - b }).Ptr())
+	if got := sub(3, 5); got != -2 {
+		t.Errorf("NewCallback2 returned %d but wanted %d", got, -2)
+	}
+
+	var mix func(a int8, f float32, b bool) int64
+	purego.RegisterFunc(&mix, purego.NewCallback3(func(a int8, f float32, b bool) int64 {
+		if !b {
+			return 0
+		}
+		return int64(a) * int64(f)
+	}).Ptr())
+	if got := mix(-3, 4, true); got != -12 {
+		t.Errorf("NewCallback3 returned %d but wanted %d", got, -12)
+	}
+
+	var x int
+	var ptrs func(p unsafe.Pointer, a, b, c float64) unsafe.Pointer
+	purego.RegisterFunc(&ptrs, purego.NewCallback4(func(p unsafe.Pointer, a, b, c float64) unsafe.Pointer {
+		*(*int)(p) = int(a + b + c)
+		return p
+	}).Ptr())
+	if got := ptrs(unsafe.Pointer(&x), 1, 2, 3); got != unsafe.Pointer(&x) || x != 6 {
+		t.Errorf("NewCallback4 returned %p and set %d but wanted %p and %d", got, x, &x, 6)
+	}
+
+	// the integer arguments after the sixth are on the stack on amd64
+	var weigh func(a, b, c, d, e, f int64, g int32, h int8) int64
+	weighCb := purego.NewCallback8(func(a, b, c, d, e, f int64, g int32, h int8) int64 {
+		return a + b*2 + c*3 + d*4 + e*5 + f*6 + int64(g)*7 + int64(h)*8
+	})
+	purego.RegisterFunc(&weigh, weighCb.Ptr())
+	if got, want := weigh(1, 1, 1, 1, 1, 1, -1, -2), int64(1+2+3+4+5+6-7-16); got != want {
+		t.Errorf("NewCallback8 returned %d but wanted %d", got, want)
+	}
+
+	used, _ := purego.CallbackSlots()
+	weighCb.Release()
+	if after, _ := purego.CallbackSlots(); after != used-1 {
+		t.Errorf("releasing a typed callback left %d callbacks in use but wanted %d", after, used-1)
+	}
+}
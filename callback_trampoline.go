@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build (freebsd || linux) && (amd64 || arm64)
+
+package purego
+
+import (
+	"encoding/binary"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// callbacksGrow reports whether callbacks past maxCB are created with newTrampoline.
+const callbacksGrow = true
+
+// trampolineSize is the size of a trampoline created by newTrampoline.
+const trampolineSize = 32
+
+// callbackasm1 is implemented in sys_GOARCH.s
+//
+//go:linkname __callbackasm1 callbackasm1
+var __callbackasm1 byte
+var callbackasm1ABI0 = uintptr(unsafe.Pointer(&__callbackasm1))
+
+// trampolines holds the addresses of mapped trampolines that aren't used yet. It is protected by cbs.lock.
+var trampolines []uintptr
+
+// newTrampoline returns the address of a trampoline that calls the callback at index.
+// It must be called with cbs.lock held and index one past the last callback.
+// The trampoline enters callbackasm1 exactly like the entry for index in the callbackasm table would.
+// On amd64 it pushes the return address of that entry's CALL and on arm64 it loads the index into R12.
+func newTrampoline(index int) uintptr {
+	if len(trampolines) == 0 {
+		trampolines = mapTrampolines(index)
+	}
+	addr := trampolines[0]
+	trampolines = trampolines[1:]
+	return addr
+}
+
+// mapTrampolines maps a page with trampolines for the callbacks from index on and returns their addresses.
+// The whole page is written before it becomes executable because it is never written again.
+func mapTrampolines(index int) []uintptr {
+	page, err := syscall.Mmap(-1, 0, syscall.Getpagesize(), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE|syscall.MAP_ANON)
+	if err != nil {
+		panic("purego: failed to map callback trampolines: " + err.Error())
+	}
+	addrs := make([]uintptr, len(page)/trampolineSize)
+	for i := range addrs {
+		code := page[i*trampolineSize : (i+1)*trampolineSize]
+		writeTrampoline(code, index+i)
+		addrs[i] = uintptr(unsafe.Pointer(&code[0]))
+	}
+	// The kernel synchronizes the instruction cache on arm64 when a page becomes executable.
+	// syscall.Mprotect doesn't exist on FreeBSD
+	if _, _, errno := syscall.Syscall(syscall.SYS_MPROTECT, uintptr(unsafe.Pointer(&page[0])), uintptr(len(page)), syscall.PROT_READ|syscall.PROT_EXEC); errno != 0 {
+		panic("purego: failed to map callback trampolines: " + errno.Error())
+	}
+	return addrs
+}
+
+// writeTrampoline writes the machine code of the trampoline for the callback at index into code.
+func writeTrampoline(code []byte, index int) {
+	le := binary.LittleEndian
+	switch runtime.GOARCH {
+	case "amd64":
+		// MOVQ $(callbackasm + 5*(index+1)), AX; PUSHQ AX; MOVQ $callbackasm1, AX; JMP AX
+		code[0], code[1] = 0x48, 0xb8
+		le.PutUint64(code[2:], uint64(callbackasmAddr(index+1)))
+		code[10] = 0x50
+		code[11], code[12] = 0x48, 0xb8
+		le.PutUint64(code[13:], uint64(callbackasm1ABI0))
+		code[21], code[22] = 0xff, 0xe0
+		for i := 23; i < len(code); i++ {
+			code[i] = 0xcc // INT3
+		}
+	case "arm64":
+		// MOVZ $(index&0xffff), R12; MOVK $(index>>16)<<16, R12; LDR 8(PC), R16; BR (R16); callbackasm1
+		le.PutUint32(code[0:], 0xd2800000|uint32(index&0xffff)<<5|12)
+		le.PutUint32(code[4:], 0xf2a00000|uint32(index>>16&0xffff)<<5|12)
+		le.PutUint32(code[8:], 0x58000050)
+		le.PutUint32(code[12:], 0xd61f0200)
+		le.PutUint64(code[16:], uint64(callbackasm1ABI0))
+	}
+}
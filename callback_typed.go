@@ -0,0 +1,195 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build darwin || freebsd || (linux && (amd64 || arm64 || loong64)) || netbsd
+
+package purego
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// NewCallback0 is like NewReleasableCallback for a function without arguments. The callbacks created by
+// NewCallback0 to NewCallback8 read their arguments and write their result without reflection
+// and don't allocate when they are called. Use them for callbacks that are called very often like
+// comparators. A callback of C that returns void may return any value which is ignored.
+//
+// The arguments that don't fit into registers, which are the integer arguments after the sixth on amd64,
+// are read from the stack in 8-byte slots like NewCallback does. On arm64 eight arguments always fit into
+// registers so the tighter packing of stack arguments on darwin/arm64 never applies.
+func NewCallback0[R Scalar](fn func() R) *Callback {
+	result := newScalarResult[R]()
+	return newTypedCallback(fn, func(a *callbackArgs) {
+		result(a, fn())
+	})
+}
+
+// NewCallback1 is like NewCallback0 for a function with one argument.
+func NewCallback1[A, R Scalar](fn func(A) R) *Callback {
+	floatA := isFloat[A]()
+	result := newScalarResult[R]()
+	return newTypedCallback(fn, func(a *callbackArgs) {
+		f := newCallbackFrame(a)
+		result(a, fn(scalarArg[A](&f, floatA)))
+	})
+}
+
+// NewCallback2 is like NewCallback0 for a function with two arguments.
+func NewCallback2[A, B, R Scalar](fn func(A, B) R) *Callback {
+	floatA, floatB := isFloat[A](), isFloat[B]()
+	result := newScalarResult[R]()
+	return newTypedCallback(fn, func(a *callbackArgs) {
+		f := newCallbackFrame(a)
+		x1 := scalarArg[A](&f, floatA)
+		result(a, fn(x1, scalarArg[B](&f, floatB)))
+	})
+}
+
+// NewCallback3 is like NewCallback0 for a function with three arguments.
+func NewCallback3[A, B, C, R Scalar](fn func(A, B, C) R) *Callback {
+	floatA, floatB, floatC := isFloat[A](), isFloat[B](), isFloat[C]()
+	result := newScalarResult[R]()
+	return newTypedCallback(fn, func(a *callbackArgs) {
+		f := newCallbackFrame(a)
+		x1 := scalarArg[A](&f, floatA)
+		x2 := scalarArg[B](&f, floatB)
+		result(a, fn(x1, x2, scalarArg[C](&f, floatC)))
+	})
+}
+
+// NewCallback4 is like NewCallback0 for a function with four arguments.
+func NewCallback4[A, B, C, D, R Scalar](fn func(A, B, C, D) R) *Callback {
+	floatA, floatB, floatC, floatD := isFloat[A](), isFloat[B](), isFloat[C](), isFloat[D]()
+	result := newScalarResult[R]()
+	return newTypedCallback(fn, func(a *callbackArgs) {
+		f := newCallbackFrame(a)
+		x1 := scalarArg[A](&f, floatA)
+		x2 := scalarArg[B](&f, floatB)
+		x3 := scalarArg[C](&f, floatC)
+		result(a, fn(x1, x2, x3, scalarArg[D](&f, floatD)))
+	})
+}
+
+// NewCallback5 is like NewCallback0 for a function with five arguments.
+func NewCallback5[A, B, C, D, E, R Scalar](fn func(A, B, C, D, E) R) *Callback {
+	floatA, floatB, floatC, floatD, floatE := isFloat[A](), isFloat[B](), isFloat[C](), isFloat[D](), isFloat[E]()
+	result := newScalarResult[R]()
+	return newTypedCallback(fn, func(a *callbackArgs) {
+		f := newCallbackFrame(a)
+		x1 := scalarArg[A](&f, floatA)
+		x2 := scalarArg[B](&f, floatB)
+		x3 := scalarArg[C](&f, floatC)
+		x4 := scalarArg[D](&f, floatD)
+		result(a, fn(x1, x2, x3, x4, scalarArg[E](&f, floatE)))
+	})
+}
+
+// NewCallback6 is like NewCallback0 for a function with six arguments.
+func NewCallback6[A, B, C, D, E, F, R Scalar](fn func(A, B, C, D, E, F) R) *Callback {
+	floatA, floatB, floatC, floatD, floatE, floatF := isFloat[A](), isFloat[B](), isFloat[C](), isFloat[D](), isFloat[E](), isFloat[F]()
+	result := newScalarResult[R]()
+	return newTypedCallback(fn, func(a *callbackArgs) {
+		f := newCallbackFrame(a)
+		x1 := scalarArg[A](&f, floatA)
+		x2 := scalarArg[B](&f, floatB)
+		x3 := scalarArg[C](&f, floatC)
+		x4 := scalarArg[D](&f, floatD)
+		x5 := scalarArg[E](&f, floatE)
+		result(a, fn(x1, x2, x3, x4, x5, scalarArg[F](&f, floatF)))
+	})
+}
+
+// NewCallback7 is like NewCallback0 for a function with seven arguments.
+func NewCallback7[A, B, C, D, E, F, G, R Scalar](fn func(A, B, C, D, E, F, G) R) *Callback {
+	floatA, floatB, floatC, floatD, floatE, floatF, floatG := isFloat[A](), isFloat[B](), isFloat[C](), isFloat[D](), isFloat[E](), isFloat[F](), isFloat[G]()
+	result := newScalarResult[R]()
+	return newTypedCallback(fn, func(a *callbackArgs) {
+		f := newCallbackFrame(a)
+		x1 := scalarArg[A](&f, floatA)
+		x2 := scalarArg[B](&f, floatB)
+		x3 := scalarArg[C](&f, floatC)
+		x4 := scalarArg[D](&f, floatD)
+		x5 := scalarArg[E](&f, floatE)
+		x6 := scalarArg[F](&f, floatF)
+		result(a, fn(x1, x2, x3, x4, x5, x6, scalarArg[G](&f, floatG)))
+	})
+}
+
+// NewCallback8 is like NewCallback0 for a function with eight arguments.
+func NewCallback8[A, B, C, D, E, F, G, H, R Scalar](fn func(A, B, C, D, E, F, G, H) R) *Callback {
+	floatA, floatB, floatC, floatD, floatE, floatF, floatG, floatH := isFloat[A](), isFloat[B](), isFloat[C](), isFloat[D](), isFloat[E](), isFloat[F](), isFloat[G](), isFloat[H]()
+	result := newScalarResult[R]()
+	return newTypedCallback(fn, func(a *callbackArgs) {
+		f := newCallbackFrame(a)
+		x1 := scalarArg[A](&f, floatA)
+		x2 := scalarArg[B](&f, floatB)
+		x3 := scalarArg[C](&f, floatC)
+		x4 := scalarArg[D](&f, floatD)
+		x5 := scalarArg[E](&f, floatE)
+		x6 := scalarArg[F](&f, floatF)
+		x7 := scalarArg[G](&f, floatG)
+		result(a, fn(x1, x2, x3, x4, x5, x6, x7, scalarArg[H](&f, floatH)))
+	})
+}
+
+// newTypedCallback returns a callback that calls typed.
+func newTypedCallback(fn any, typed func(a *callbackArgs)) *Callback {
+	if reflect.ValueOf(fn).IsNil() {
+		panic("purego: function must not be nil")
+	}
+	return addCallback(&callbackEntry{typed: typed})
+}
+
+// newCallbackFrame returns the frame of the arguments of a.
+func newCallbackFrame(a *callbackArgs) callbackFrame {
+	return callbackFrame{
+		frame: (*[callbackMaxFrame]uintptr)(a.args),
+		stack: numOfIntegerRegisters() + numOfFloatRegisters,
+	}
+}
+
+// scalarArg reads the next argument of type T from f. float is the result of isFloat for T
+// which is looked up once so that reading doesn't need reflection.
+func scalarArg[T Scalar](f *callbackFrame, float bool) T {
+	if float {
+		return *(*T)(unsafe.Pointer(&f.frame[f.nextFloat()]))
+	}
+	return *(*T)(unsafe.Pointer(&f.frame[f.nextInt()]))
+}
+
+// newScalarResult returns a function that stores a result of type T in a like callbackWrap does.
+// Signed integers are sign extended and floats are stored in the first floating-point register.
+func newScalarResult[T Scalar]() func(a *callbackArgs, r T) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	size := t.Size()
+	switch k := t.Kind(); {
+	case k == reflect.Float32:
+		return func(a *callbackArgs, r T) {
+			a.floatResult[0] = uintptr(*(*uint32)(unsafe.Pointer(&r)))
+		}
+	case k == reflect.Float64:
+		return func(a *callbackArgs, r T) {
+			a.floatResult[0] = uintptr(*(*uint64)(unsafe.Pointer(&r)))
+		}
+	case k == reflect.Int || k == reflect.Int8 || k == reflect.Int16 || k == reflect.Int32 || k == reflect.Int64:
+		shift := (8 - size) * 8
+		return func(a *callbackArgs, r T) {
+			var u uint64
+			*(*T)(unsafe.Pointer(&u)) = r
+			a.result = uintptr(int64(u<<shift) >> shift)
+		}
+	default:
+		return func(a *callbackArgs, r T) {
+			var u uint64
+			*(*T)(unsafe.Pointer(&u)) = r
+			a.result = uintptr(u)
+		}
+	}
+}
+
+// isFloat reports whether T is passed in a floating-point register.
+func isFloat[T Scalar]() bool {
+	k := reflect.TypeOf((*T)(nil)).Elem().Kind()
+	return k == reflect.Float32 || k == reflect.Float64
+}
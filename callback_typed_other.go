@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build windows || (linux && !amd64 && !arm64 && !loong64)
+
+package purego
+
+// NewCallback0 is NewCallback on this platform. Release of the Callback does nothing.
+func NewCallback0[R Scalar](fn func() R) *Callback {
+	return newUnreleasableCallback(NewCallback(fn))
+}
+
+// NewCallback1 is NewCallback on this platform. Release of the Callback does nothing.
+func NewCallback1[A, R Scalar](fn func(A) R) *Callback {
+	return newUnreleasableCallback(NewCallback(fn))
+}
+
+// NewCallback2 is NewCallback on this platform. Release of the Callback does nothing.
+func NewCallback2[A, B, R Scalar](fn func(A, B) R) *Callback {
+	return newUnreleasableCallback(NewCallback(fn))
+}
+
+// NewCallback3 is NewCallback on this platform. Release of the Callback does nothing.
+func NewCallback3[A, B, C, R Scalar](fn func(A, B, C) R) *Callback {
+	return newUnreleasableCallback(NewCallback(fn))
+}
+
+// NewCallback4 is NewCallback on this platform. Release of the Callback does nothing.
+func NewCallback4[A, B, C, D, R Scalar](fn func(A, B, C, D) R) *Callback {
+	return newUnreleasableCallback(NewCallback(fn))
+}
+
+// NewCallback5 is NewCallback on this platform. Release of the Callback does nothing.
+func NewCallback5[A, B, C, D, E, R Scalar](fn func(A, B, C, D, E) R) *Callback {
+	return newUnreleasableCallback(NewCallback(fn))
+}
+
+// NewCallback6 is NewCallback on this platform. Release of the Callback does nothing.
+func NewCallback6[A, B, C, D, E, F, R Scalar](fn func(A, B, C, D, E, F) R) *Callback {
+	return newUnreleasableCallback(NewCallback(fn))
+}
+
+// NewCallback7 is NewCallback on this platform. Release of the Callback does nothing.
+func NewCallback7[A, B, C, D, E, F, G, R Scalar](fn func(A, B, C, D, E, F, G) R) *Callback {
+	return newUnreleasableCallback(NewCallback(fn))
+}
+
+// NewCallback8 is NewCallback on this platform. Release of the Callback does nothing.
+func NewCallback8[A, B, C, D, E, F, G, H, R Scalar](fn func(A, B, C, D, E, F, G, H) R) *Callback {
+	return newUnreleasableCallback(NewCallback(fn))
+}
+
+// newUnreleasableCallback returns a Callback of ptr whose Release does nothing.
+func newUnreleasableCallback(ptr uintptr) *Callback {
+	return &Callback{ptr: ptr, index: -1}
+}
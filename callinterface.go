@@ -0,0 +1,158 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build darwin || freebsd || linux || netbsd || windows
+
+package purego
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"unsafe"
+)
+
+// Type describes a C type of a CallInterface argument or result.
+// The zero value is void which is only valid as a result.
+type Type struct {
+	typ reflect.Type
+}
+
+// Descriptors of the C scalar types and char*. The complex, 128-bit and long double types have
+// the same platform restrictions as complex64, Int128, Uint128 and LongDouble in RegisterFunc.
+var (
+	TypeVoid    = Type{}
+	TypeBool    = Type{reflect.TypeOf(false)}
+	TypeInt8    = Type{reflect.TypeOf(int8(0))}
+	TypeInt16   = Type{reflect.TypeOf(int16(0))}
+	TypeInt32   = Type{reflect.TypeOf(int32(0))}
+	TypeInt64   = Type{reflect.TypeOf(int64(0))}
+	TypeUint8   = Type{reflect.TypeOf(uint8(0))}
+	TypeUint16  = Type{reflect.TypeOf(uint16(0))}
+	TypeUint32  = Type{reflect.TypeOf(uint32(0))}
+	TypeUint64  = Type{reflect.TypeOf(uint64(0))}
+	TypeFloat   = Type{reflect.TypeOf(float32(0))}
+	TypeDouble  = Type{reflect.TypeOf(float64(0))}
+	TypePointer = Type{reflect.TypeOf(unsafe.Pointer(nil))}
+	TypeString  = Type{reflect.TypeOf("")} // char* converted from and to a Go string
+
+	TypeComplexFloat  = Type{reflect.TypeOf(complex64(0))}
+	TypeComplexDouble = Type{reflect.TypeOf(complex128(0))}
+	TypeInt128        = Type{reflect.TypeOf(Int128{})}
+	TypeUint128       = Type{reflect.TypeOf(Uint128{})}
+	TypeLongDouble    = Type{reflect.TypeOf(LongDouble{})}
+)
+
+// TypeStruct returns the descriptor of a C struct with the given fields in order.
+// The fields are laid out with their natural alignment like C does.
+func TypeStruct(fields ...Type) Type {
+	sf := make([]reflect.StructField, len(fields))
+	for i, f := range fields {
+		if f.typ == nil {
+			panic("purego: struct field can't be void")
+		}
+		sf[i] = reflect.StructField{Name: "F" + strconv.Itoa(i), Type: f.typ}
+	}
+	return Type{reflect.StructOf(sf)}
+}
+
+// TypeUnion returns the descriptor of a C union of members. Its Go type is a struct with a Union
+// field followed by the members F0, F1, and so on.
+func TypeUnion(members ...Type) Type {
+	sf := make([]reflect.StructField, len(members)+1)
+	sf[0] = reflect.StructField{Name: "Union", Type: unionType}
+	for i, m := range members {
+		if m.typ == nil {
+			panic("purego: union member can't be void")
+		}
+		sf[i+1] = reflect.StructField{Name: "F" + strconv.Itoa(i), Type: m.typ}
+	}
+	return Type{reflect.StructOf(sf)}
+}
+
+// GoType returns the Go type that holds values of t, or nil for void.
+// A struct is represented by a Go struct with the fields F0, F1, and so on.
+func (t Type) GoType() reflect.Type {
+	return t.typ
+}
+
+// CallInterface describes the signature of a C function that is only known at runtime.
+// It is prepared once with NewCallInterface and can then call any C function with that signature.
+// The arguments are passed following the same rules as RegisterFunc.
+type CallInterface struct {
+	f *cFunc
+}
+
+// NewCallInterface prepares calling C functions that return ret and take args.
+// It panics if the signature isn't supported on this platform.
+func NewCallInterface(ret Type, args ...Type) *CallInterface {
+	in := make([]reflect.Type, len(args))
+	for i, a := range args {
+		if a.typ == nil {
+			panic("purego: void is only allowed as a result")
+		}
+		in[i] = a.typ
+	}
+	var out []reflect.Type
+	if ret.typ != nil {
+		out = []reflect.Type{ret.typ}
+	}
+	return &CallInterface{f: newCFunc(reflect.FuncOf(in, out, false), 0)}
+}
+
+// Call calls the C function fn with args. Each argument must be convertible to the Go type of
+// the corresponding argument descriptor; nil is a NULL pointer. It returns the result as the Go type
+// of the result descriptor or nil for void.
+func (ci *CallInterface) Call(fn uintptr, args ...any) any {
+	ty := ci.f.ty
+	if len(args) != ty.NumIn() {
+		panic(fmt.Sprintf("purego: wrong number of arguments: got %d but wanted %d", len(args), ty.NumIn()))
+	}
+	values := make([]reflect.Value, len(args))
+	for i, a := range args {
+		in := ty.In(i)
+		v := reflect.ValueOf(a)
+		switch {
+		case !v.IsValid():
+			v = reflect.Zero(in)
+		case v.Type() == in:
+		case v.Type().ConvertibleTo(in) && (v.Kind() == reflect.String) == (in.Kind() == reflect.String):
+			v = v.Convert(in)
+		case in.Kind() == reflect.UnsafePointer && v.Kind() == reflect.Slice:
+			v = reflect.ValueOf(v.UnsafePointer())
+		default:
+			panic(fmt.Sprintf("purego: cannot use %s as argument %d of type %s", v.Type(), i, in))
+		}
+		values[i] = v
+	}
+	results := ci.call(fn, values)
+	if len(results) == 0 {
+		return nil
+	}
+	return results[0].Interface()
+}
+
+// CallValues is like Call but takes the arguments as values of exactly the Go types of the argument
+// descriptors and returns the results the same way. It avoids converting each argument.
+func (ci *CallInterface) CallValues(fn uintptr, args []reflect.Value) []reflect.Value {
+	if len(args) != ci.f.ty.NumIn() {
+		panic(fmt.Sprintf("purego: wrong number of arguments: got %d but wanted %d", len(args), ci.f.ty.NumIn()))
+	}
+	for i, a := range args {
+		if a.Type() != ci.f.ty.In(i) {
+			panic(fmt.Sprintf("purego: cannot use %s as argument %d of type %s", a.Type(), i, ci.f.ty.In(i)))
+		}
+	}
+	// call reuses the slice for the results
+	return ci.call(fn, append([]reflect.Value(nil), args...))
+}
+
+func (ci *CallInterface) call(fn uintptr, args []reflect.Value) []reflect.Value {
+	if fn == 0 {
+		panic("purego: fn is nil")
+	}
+	f := *ci.f
+	f.cfn = fn
+	f.isCallback = isCallbackFunction(fn)
+	return f.call(args)
+}
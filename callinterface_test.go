@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+package purego_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/ebitengine/purego"
+	"github.com/ebitengine/purego/internal/load"
+)
+
+func TestCallInterface(t *testing.T) {
+	if runtime.GOARCH != "arm64" && runtime.GOARCH != "amd64" && runtime.GOARCH != "loong64" {
+		t.Skip("Platform doesn't support Floats")
+	}
+	libFileName := filepath.Join(t.TempDir(), "abitest.so")
+	if err := buildSharedLib("CC", libFileName, filepath.Join("testdata", "abitest", "abi_test.c")); err != nil {
+		t.Fatal(err)
+	}
+	lib, err := load.OpenLibrary(libFileName)
+	if err != nil {
+		t.Fatalf("Failed to open library %q: %v", libFileName, err)
+	}
+	t.Cleanup(func() {
+		if err := load.CloseLibrary(lib); err != nil {
+			t.Errorf("Failed to close library: %v", err)
+		}
+	})
+
+	mixed, err := load.OpenSymbol(lib, "mixed_int_float_double")
+	if err != nil {
+		t.Fatalf("Failed to find mixed_int_float_double: %v", err)
+	}
+	ci := purego.NewCallInterface(purego.TypeInt64, purego.TypeInt32, purego.TypeFloat, purego.TypeInt32, purego.TypeDouble)
+	// untyped constants are converted to the argument types
+	if got := ci.Call(mixed, 1, 0.5, 2, 3); got != int64(3206) {
+		t.Errorf("mixed_int_float_double failed. got %v but wanted %d", got, 3206)
+	}
+	args := []reflect.Value{reflect.ValueOf(int32(4)), reflect.ValueOf(float32(0.1)), reflect.ValueOf(int32(5)), reflect.ValueOf(6.0)}
+	if got := ci.CallValues(mixed, args)[0].Int(); got != 6505 {
+		t.Errorf("mixed_int_float_double failed. got %d but wanted %d", got, 6505)
+	}
+	if got := args[0].Int(); got != 4 {
+		t.Errorf("CallValues modified its arguments. got %d but wanted %d", got, 4)
+	}
+
+	buf := make([]byte, 64)
+	stack, err := load.OpenSymbol(lib, "stack_10_int32")
+	if err != nil {
+		t.Fatalf("Failed to find stack_10_int32: %v", err)
+	}
+	int32s := make([]purego.Type, 10)
+	for i := range int32s {
+		int32s[i] = purego.TypeInt32
+	}
+	ci = purego.NewCallInterface(purego.TypeVoid, append([]purego.Type{purego.TypePointer, purego.TypeUint64}, int32s...)...)
+	if got := ci.Call(stack, buf, len(buf), 1, 2, 3, 4, 5, 6, 7, 8, 9, 10); got != nil {
+		t.Errorf("void function returned %v", got)
+	}
+	if got, want := string(buf[:20]), "1:2:3:4:5:6:7:8:9:10"; got != want {
+		t.Errorf("stack_10_int32 failed. got %q but wanted %q", got, want)
+	}
+}
+
+func TestCallInterface_Struct(t *testing.T) {
+	if (runtime.GOOS != "darwin" && runtime.GOOS != "linux") || (runtime.GOARCH != "arm64" && runtime.GOARCH != "amd64") {
+		t.Skip("struct arguments are only supported on Darwin and Linux ARM64/AMD64")
+	}
+	libFileName := filepath.Join(t.TempDir(), "structreturntest.so")
+	if err := buildSharedLib("CC", libFileName, filepath.Join("testdata", "structtest", "structreturn_test.c")); err != nil {
+		t.Fatal(err)
+	}
+	lib, err := load.OpenLibrary(libFileName)
+	if err != nil {
+		t.Fatalf("Failed to open library %q: %v", libFileName, err)
+	}
+	t.Cleanup(func() {
+		if err := load.CloseLibrary(lib); err != nil {
+			t.Errorf("Failed to close library: %v", err)
+		}
+	})
+
+	fn, err := load.OpenSymbol(lib, "ReturnMixed4")
+	if err != nil {
+		t.Fatalf("Failed to find ReturnMixed4: %v", err)
+	}
+	mixed4 := purego.TypeStruct(purego.TypeDouble, purego.TypeUint32, purego.TypeFloat)
+	ci := purego.NewCallInterface(mixed4, purego.TypeDouble, purego.TypeUint32, purego.TypeFloat)
+	got := reflect.ValueOf(ci.Call(fn, 1.5, 2, 3.5))
+	if got.Type() != mixed4.GoType() {
+		t.Fatalf("ReturnMixed4 returned %s but wanted %s", got.Type(), mixed4.GoType())
+	}
+	if a, b, c := got.Field(0).Float(), got.Field(1).Uint(), got.Field(2).Float(); a != 1.5 || b != 2 || c != 3.5 {
+		t.Errorf("ReturnMixed4 failed. got {%f, %d, %f} but wanted {1.5, 2, 3.5}", a, b, c)
+	}
+
+	fn, err = load.OpenSymbol(lib, "ReturnUnionFloats")
+	if err != nil {
+		t.Fatalf("Failed to find ReturnUnionFloats: %v", err)
+	}
+	floats := purego.TypeUnion(purego.TypeFloat, purego.TypeStruct(purego.TypeFloat, purego.TypeFloat))
+	ci = purego.NewCallInterface(floats, purego.TypeFloat, purego.TypeFloat)
+	got = reflect.ValueOf(ci.Call(fn, 2, 3))
+	if f, a, b := got.Field(1).Float(), got.Field(2).Field(0).Float(), got.Field(2).Field(1).Float(); f != 2 || a != 2 || b != 3 {
+		t.Errorf("ReturnUnionFloats failed. got {%f, {%f, %f}} but wanted {2, {2, 3}}", f, a, b)
+	}
+}
@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build darwin || freebsd || linux || netbsd || windows
+
+package purego
+
+import (
+	"math"
+	"reflect"
+	"runtime"
+)
+
+// The C scalar types below have the width and signedness of the C type on the target platform.
+// Use them instead of Go integers of a fixed size to write bindings that are correct everywhere.
+// CLong, CULong, CChar and CWChar are defined per platform.
+
+// CInt is a C int.
+type CInt int32
+
+// CUInt is a C unsigned int.
+type CUInt uint32
+
+// CSizeT is a C size_t.
+type CSizeT uintptr
+
+// Int128 is a C __int128. It is passed in a pair of integer registers like a struct
+// aligned to 16 bytes. Lo holds the lower 64 bits and Hi the upper 64 bits.
+type Int128 struct {
+	Lo uint64 `c:"align=16"`
+	Hi int64
+}
+
+// Uint128 is a C unsigned __int128. Lo holds the lower 64 bits and Hi the upper 64 bits.
+type Uint128 struct {
+	Lo uint64 `c:"align=16"`
+	Hi uint64
+}
+
+// LongDouble is a C long double on amd64 which has the 80-bit extended precision format of x87.
+// It is passed in memory and returned on the x87 stack. Use NewLongDouble and Float64 to convert
+// it from and to a float64.
+//
+// LongDouble is only supported on amd64.
+type LongDouble struct {
+	mantissa     uint64 `c:"align=16"` // with an explicit integer bit
+	signExponent uint16
+}
+
+// NewLongDouble returns the long double that is equal to f.
+func NewLongDouble(f float64) LongDouble {
+	bits := math.Float64bits(f)
+	sign := uint16(bits>>63) << 15
+	exp := int(bits>>52) & 0x7ff
+	frac := bits & (1<<52 - 1)
+	switch {
+	case exp == 0 && frac == 0:
+		return LongDouble{signExponent: sign}
+	case exp == 0x7ff:
+		// infinity or NaN
+		return LongDouble{mantissa: 1<<63 | frac<<11, signExponent: sign | 0x7fff}
+	case exp == 0:
+		// a subnormal float64 is a normal long double
+		exp = 1
+		for frac&(1<<52) == 0 {
+			frac <<= 1
+			exp--
+		}
+	default:
+		frac |= 1 << 52
+	}
+	return LongDouble{mantissa: frac << 11, signExponent: sign | uint16(exp-1023+16383)}
+}
+
+// Float64 returns l rounded to the nearest float64.
+func (l LongDouble) Float64() float64 {
+	sign := 1.0
+	if l.signExponent&0x8000 != 0 {
+		sign = -1
+	}
+	exp := int(l.signExponent & 0x7fff)
+	switch {
+	case exp == 0x7fff && l.mantissa<<1 == 0:
+		return math.Inf(int(sign))
+	case exp == 0x7fff:
+		return math.NaN()
+	case l.mantissa == 0:
+		return math.Copysign(0, sign)
+	}
+	return math.Copysign(math.Ldexp(float64(l.mantissa), exp-16383-63), sign)
+}
+
+// complex64Parts and complex128Parts have the memory layout of float _Complex and double _Complex
+// which are passed like a struct of their real and imaginary parts.
+type complex64Parts struct{ re, im float32 }
+type complex128Parts struct{ re, im float64 }
+
+var (
+	complex64PartsType  = reflect.TypeOf(complex64Parts{})
+	complex128PartsType = reflect.TypeOf(complex128Parts{})
+	longDoubleCType     = cStructType(reflect.TypeOf(LongDouble{}))
+)
+
+// complexParts returns the struct with the real and imaginary parts of v which is a complex number.
+func complexParts(v reflect.Value) reflect.Value {
+	c := v.Complex()
+	if v.Kind() == reflect.Complex64 {
+		return reflect.ValueOf(complex64Parts{float32(real(c)), float32(imag(c))})
+	}
+	return reflect.ValueOf(complex128Parts{real(c), imag(c)})
+}
+
+// complexPartsType returns the type of the struct returned by complexParts for the complex type t.
+func complexPartsType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Complex64 {
+		return complex64PartsType
+	}
+	return complex128PartsType
+}
+
+// setComplex sets the complex number v to the real and imaginary parts in parts.
+func setComplex(v, parts reflect.Value) {
+	v.SetComplex(complex(parts.Field(0).Float(), parts.Field(1).Float()))
+}
+
+// containsLongDouble reports whether t, a type returned by cStructType, contains a long double.
+func containsLongDouble(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct:
+		if t == longDoubleCType {
+			return true
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if containsLongDouble(t.Field(i).Type) {
+				return true
+			}
+		}
+	case reflect.Array:
+		return t.Len() > 0 && containsLongDouble(t.Elem())
+	}
+	return false
+}
+
+// returnsLongDouble reports whether a struct of type t, a type returned by cStructType,
+// is returned on the x87 stack. That is a long double alone or wrapped in a struct.
+func returnsLongDouble(t reflect.Type) bool {
+	return runtime.GOARCH == "amd64" && t.Size() == 16 && containsLongDouble(t)
+}
+
+// checkLongDouble panics if t, a type returned by cStructType, contains a long double
+// on a platform that doesn't support it.
+func checkLongDouble(t reflect.Type) {
+	if runtime.GOARCH != "amd64" && containsLongDouble(t) {
+		panic("purego: long double is only supported on amd64")
+	}
+}
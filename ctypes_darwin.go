@@ -0,0 +1,16 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+package purego
+
+// CLong is a C long which is 64 bits wide on macOS.
+type CLong int64
+
+// CULong is a C unsigned long which is 64 bits wide on macOS.
+type CULong uint64
+
+// CChar is a C char which is signed on macOS.
+type CChar int8
+
+// CWChar is a C wchar_t which is a signed 32-bit integer on macOS.
+type CWChar int32
@@ -0,0 +1,12 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build (freebsd || linux || netbsd) && (386 || arm)
+
+package purego
+
+// CLong is a C long which is as wide as a pointer on Unix.
+type CLong int32
+
+// CULong is a C unsigned long which is as wide as a pointer on Unix.
+type CULong uint32
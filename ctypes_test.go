@@ -0,0 +1,206 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build (darwin || linux) && (arm64 || amd64)
+
+package purego_test
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"unsafe"
+
+	"github.com/ebitengine/purego"
+)
+
+func TestRegisterFunc_Complex(t *testing.T) {
+	lib := buildCTypesTest(t)
+
+	var ComplexFloatMul func(a, b complex64) complex64
+	purego.RegisterLibFunc(&ComplexFloatMul, lib, "ComplexFloatMul")
+	if got, want := ComplexFloatMul(1+2i, 3-1i), complex64(5+5i); got != want {
+		t.Errorf("ComplexFloatMul returned %v wanted %v", got, want)
+	}
+
+	var ComplexDoubleAdd func(a, b complex128, c float64) complex128
+	purego.RegisterLibFunc(&ComplexDoubleAdd, lib, "ComplexDoubleAdd")
+	if got, want := ComplexDoubleAdd(1+2i, 3+4i, 0.5), 4.5+6i; got != want {
+		t.Errorf("ComplexDoubleAdd returned %v wanted %v", got, want)
+	}
+
+	var ComplexDoubleParts func(a complex128) float64
+	purego.RegisterLibFunc(&ComplexDoubleParts, lib, "ComplexDoubleParts")
+	if got, want := ComplexDoubleParts(7+3i), 4.0; got != want {
+		t.Errorf("ComplexDoubleParts returned %v wanted %v", got, want)
+	}
+
+	type FloatComplex struct {
+		c complex64
+		x float32
+	}
+	var FloatComplexSum func(s FloatComplex) float32
+	purego.RegisterLibFunc(&FloatComplexSum, lib, "FloatComplexSum")
+	if got, want := FloatComplexSum(FloatComplex{c: 1 + 2i, x: 7}), float32(10); got != want {
+		t.Errorf("FloatComplexSum returned %v wanted %v", got, want)
+	}
+}
+
+func TestRegisterFunc_Int128(t *testing.T) {
+	lib := buildCTypesTest(t)
+
+	var Int128Add func(a, b purego.Int128) purego.Int128
+	purego.RegisterLibFunc(&Int128Add, lib, "Int128Add")
+	// -1 + 1<<64 + 2 = 1<<64 + 1
+	if got, want := Int128Add(purego.Int128{Lo: math.MaxUint64, Hi: -1}, purego.Int128{Lo: 2, Hi: 1}), (purego.Int128{Lo: 1, Hi: 1}); got != want {
+		t.Errorf("Int128Add returned %+v wanted %+v", got, want)
+	}
+
+	var Int128AfterFiveInts func(a, b, c, d, e int64, x purego.Int128) purego.Int128
+	purego.RegisterLibFunc(&Int128AfterFiveInts, lib, "Int128AfterFiveInts")
+	if got, want := Int128AfterFiveInts(1, 2, 3, 4, 5, purego.Int128{Lo: 10, Hi: 3}), (purego.Int128{Lo: 25, Hi: 3}); got != want {
+		t.Errorf("Int128AfterFiveInts returned %+v wanted %+v", got, want)
+	}
+
+	var Uint128Shift func(a purego.Uint128, n int32) purego.Uint128
+	purego.RegisterLibFunc(&Uint128Shift, lib, "Uint128Shift")
+	if got, want := Uint128Shift(purego.Uint128{Lo: 1<<63 | 1}, 4), (purego.Uint128{Lo: 16, Hi: 8}); got != want {
+		t.Errorf("Uint128Shift returned %+v wanted %+v", got, want)
+	}
+}
+
+func TestRegisterFunc_LongDouble(t *testing.T) {
+	if runtime.GOARCH != "amd64" {
+		t.Skip("long double is only supported on amd64")
+	}
+	lib := buildCTypesTest(t)
+
+	var LongDoubleScale func(a purego.LongDouble, n int32) purego.LongDouble
+	purego.RegisterLibFunc(&LongDoubleScale, lib, "LongDoubleScale")
+	// the x87 stack only has 8 registers so this fails if the result isn't popped
+	for i := 0; i < 10; i++ {
+		if got, want := LongDoubleScale(purego.NewLongDouble(1.5), int32(i)).Float64(), 1.5*float64(i); got != want {
+			t.Fatalf("LongDoubleScale returned %v wanted %v", got, want)
+		}
+	}
+
+	var LongDoubleAfterInt func(a, b, c, d, e, f, g int64, x purego.LongDouble) int64
+	purego.RegisterLibFunc(&LongDoubleAfterInt, lib, "LongDoubleAfterInt")
+	if got, want := LongDoubleAfterInt(1, 2, 3, 4, 5, 6, 7, purego.NewLongDouble(-128)), int64(-100); got != want {
+		t.Errorf("LongDoubleAfterInt returned %v wanted %v", got, want)
+	}
+
+	var LongDoublePrecise func() purego.LongDouble
+	purego.RegisterLibFunc(&LongDoublePrecise, lib, "LongDoublePrecise")
+	if got, want := LongDoublePrecise(), purego.NewLongDouble(1); got == want || got.Float64() != 1 {
+		t.Errorf("LongDoublePrecise returned %+v which isn't 1+2^-60", got)
+	}
+
+	// a float call right after a long double call must not read its result from the x87 stack
+	var DoubleAbs func(float64) float64
+	purego.RegisterLibFunc(&DoubleAbs, lib, "DoubleAbs")
+	sym, err := purego.Dlsym(lib, "DoubleAbs")
+	if err != nil {
+		t.Fatalf("failed to find DoubleAbs: %s", err)
+	}
+	boundDoubleAbs := purego.Bind1[float64, float64](sym)
+	for i := 0; i < 10; i++ {
+		LongDoubleScale(purego.NewLongDouble(1.5), 2)
+		if got := DoubleAbs(-3); got != 3 {
+			t.Fatalf("DoubleAbs(-3) after a long double call returned %v wanted 3", got)
+		}
+		LongDoubleScale(purego.NewLongDouble(1.5), 2)
+		if got := boundDoubleAbs(-3); got != 3 {
+			t.Fatalf("bound DoubleAbs(-3) after a long double call returned %v wanted 3", got)
+		}
+	}
+}
+
+func TestLongDouble_Float64(t *testing.T) {
+	for _, f := range []float64{0, math.Copysign(0, -1), 1, -2.5, math.MaxFloat64, math.SmallestNonzeroFloat64, 0x1p-1050, math.Inf(1), math.Inf(-1)} {
+		if got := purego.NewLongDouble(f).Float64(); got != f || math.Signbit(got) != math.Signbit(f) {
+			t.Errorf("NewLongDouble(%v).Float64() = %v", f, got)
+		}
+	}
+	if got := purego.NewLongDouble(math.NaN()).Float64(); !math.IsNaN(got) {
+		t.Errorf("NewLongDouble(NaN).Float64() = %v", got)
+	}
+}
+
+func buildCTypesTest(t *testing.T) uintptr {
+	t.Helper()
+	libFileName := filepath.Join(t.TempDir(), "ctypestest.so")
+	if err := buildSharedLib("CC", libFileName, filepath.Join("testdata", "ctypestest", "ctypes_test.c")); err != nil {
+		t.Fatal(err)
+	}
+	lib, err := purego.Dlopen(libFileName, purego.RTLD_NOW|purego.RTLD_GLOBAL)
+	if err != nil {
+		t.Fatalf("Dlopen(%q) failed: %v", libFileName, err)
+	}
+	t.Cleanup(func() {
+		if err := purego.Dlclose(lib); err != nil {
+			t.Errorf("Dlclose failed: %v", err)
+		}
+	})
+	return lib
+}
+
+func TestCScalarTypes(t *testing.T) {
+	lib := buildCTypesTest(t)
+
+	var CTypeSize func(which purego.CInt) purego.CSizeT
+	purego.RegisterLibFunc(&CTypeSize, lib, "CTypeSize")
+	var CTypeSigned func(which purego.CInt) purego.CInt
+	purego.RegisterLibFunc(&CTypeSigned, lib, "CTypeSigned")
+	for i, v := range []any{purego.CChar(0), purego.CInt(0), purego.CLong(0), purego.CSizeT(0), purego.CWChar(0)} {
+		typ := reflect.TypeOf(v)
+		if got, want := CTypeSize(purego.CInt(i)), purego.CSizeT(typ.Size()); got != want {
+			t.Errorf("%s is %d bytes but the C type is %d bytes", typ, want, got)
+		}
+		signed := reflect.ValueOf(v).CanInt()
+		if got := CTypeSigned(purego.CInt(i)); (got != 0) != signed {
+			t.Errorf("%s is signed=%t but the C type is signed=%t", typ, signed, got != 0)
+		}
+	}
+
+	// the results are sign or zero extended from the width of the C type
+	var LongMin func() purego.CLong
+	purego.RegisterLibFunc(&LongMin, lib, "LongMin")
+	if got, want := LongMin(), purego.CLong(-1)<<(8*unsafe.Sizeof(purego.CLong(0))-1); got != want {
+		t.Errorf("LongMin returned %d wanted %d", got, want)
+	}
+	var ULongMax func() purego.CULong
+	purego.RegisterLibFunc(&ULongMax, lib, "ULongMax")
+	if got, want := ULongMax(), ^purego.CULong(0); got != want {
+		t.Errorf("ULongMax returned %d wanted %d", got, want)
+	}
+	var CharMax func() purego.CChar
+	purego.RegisterLibFunc(&CharMax, lib, "CharMax")
+	want := ^purego.CChar(0)
+	if want < 0 {
+		want = math.MaxInt8
+	}
+	if got := CharMax(); got != want {
+		t.Errorf("CharMax returned %d wanted %d", got, want)
+	}
+
+	var ApplyLong func(f uintptr, x purego.CLong, c purego.CChar) purego.CLong
+	purego.RegisterLibFunc(&ApplyLong, lib, "ApplyLong")
+	cb := purego.NewCallback(func(x purego.CLong, c purego.CChar) purego.CLong { return x * purego.CLong(c) })
+	if got := ApplyLong(cb, -7, 3); got != -21 {
+		t.Errorf("ApplyLong returned %d wanted %d", got, -21)
+	}
+
+	type Text struct {
+		c purego.CChar
+		l purego.CLong
+		w purego.CWChar
+	}
+	var TextSum func(Text) purego.CLong
+	purego.RegisterLibFunc(&TextSum, lib, "TextSum")
+	if got := TextSum(Text{c: 'a', l: -100, w: 'é'}); got != 'a'-100+'é' {
+		t.Errorf("TextSum returned %d wanted %d", got, 'a'-100+'é')
+	}
+}
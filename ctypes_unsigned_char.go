@@ -0,0 +1,9 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build (freebsd || linux || netbsd) && (arm || arm64 || ppc64 || ppc64le || riscv64 || s390x)
+
+package purego
+
+// CChar is a C char which is unsigned on ARM, PowerPC, RISC-V and s390x.
+type CChar uint8
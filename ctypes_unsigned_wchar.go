@@ -0,0 +1,9 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build (freebsd || linux || netbsd) && (arm || arm64)
+
+package purego
+
+// CWChar is a C wchar_t which is an unsigned 32-bit integer on ARM.
+type CWChar uint32
@@ -0,0 +1,16 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+package purego
+
+// CLong is a C long which is 32 bits wide on Windows.
+type CLong int32
+
+// CULong is a C unsigned long which is 32 bits wide on Windows.
+type CULong uint32
+
+// CChar is a C char which is signed on Windows.
+type CChar int8
+
+// CWChar is a C wchar_t which holds a UTF-16 code unit on Windows.
+type CWChar uint16
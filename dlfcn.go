@@ -23,6 +23,7 @@ func init() {
 	RegisterFunc(&fnDlsym, dlsymABI0)
 	RegisterFunc(&fnDlerror, dlerrorABI0)
 	RegisterFunc(&fnDlclose, dlcloseABI0)
+	errnoLocationABI0 = fnDlsym(RTLD_DEFAULT, errnoLocationSymbol)
 }
 
 // Dlopen examines the dynamic library or bundle file specified by path. If the file is compatible
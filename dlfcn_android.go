@@ -17,6 +17,13 @@ const (
 	RTLD_GLOBAL  = is64bit*0x00100 | is32bit*0x00000002
 )
 
+// errnoLocationSymbol is the libc function returning the address of the thread's errno.
+const errnoLocationSymbol = "__errno"
+
+func init() {
+	errnoLocationABI0, _ = cgo.Dlsym(RTLD_DEFAULT, errnoLocationSymbol)
+}
+
 func Dlopen(path string, mode int) (uintptr, error) {
 	return cgo.Dlopen(path, mode)
 }
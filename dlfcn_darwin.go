@@ -13,6 +13,9 @@ const (
 	RTLD_GLOBAL  = 0x8       // All symbols are available for relocation processing of other modules.
 )
 
+// errnoLocationSymbol is the libc function returning the address of the thread's errno.
+const errnoLocationSymbol = "__error"
+
 //go:cgo_import_dynamic purego_dlopen dlopen "/usr/lib/libSystem.B.dylib"
 //go:cgo_import_dynamic purego_dlsym dlsym "/usr/lib/libSystem.B.dylib"
 //go:cgo_import_dynamic purego_dlerror dlerror "/usr/lib/libSystem.B.dylib"
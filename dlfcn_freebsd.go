@@ -12,3 +12,6 @@ const (
 	RTLD_LOCAL   = 0x00000000             // All symbols are not made available for relocation processing by other modules.
 	RTLD_GLOBAL  = 0x00000100             // All symbols are available for relocation processing of other modules.
 )
+
+// errnoLocationSymbol is the libc function returning the address of the thread's errno.
+const errnoLocationSymbol = "__error"
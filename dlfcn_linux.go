@@ -14,3 +14,6 @@ const (
 	RTLD_LOCAL   = 0x00000 // All symbols are not made available for relocation processing by other modules.
 	RTLD_GLOBAL  = 0x00100 // All symbols are available for relocation processing of other modules.
 )
+
+// errnoLocationSymbol is the libc function returning the address of the thread's errno.
+const errnoLocationSymbol = "__errno_location"
@@ -11,6 +11,7 @@ import (
 	"reflect"
 	"runtime"
 	"sync"
+	"syscall"
 	"unsafe"
 
 	"github.com/ebitengine/purego/internal/strings"
@@ -22,10 +23,19 @@ const (
 	align8ByteSize = 8 // 8-byte alignment boundary
 )
 
+// thePool holds zeroed syscall15Args. The fast paths only set the fields they use
+// so every syscall15Args must be cleared with putSyscall before it is put back.
 var thePool = sync.Pool{New: func() any {
 	return new(syscall15Args)
 }}
 
+// putSyscall clears syscall so that no argument, stack argument or flag of the last call
+// leaks into the next one and puts it back into thePool.
+func putSyscall(syscall *syscall15Args) {
+	*syscall = syscall15Args{}
+	thePool.Put(syscall)
+}
+
 // RegisterLibFunc is a wrapper around RegisterFunc that uses the C function returned from Dlsym(handle, name).
 // It panics if it can't find the name symbol.
 func RegisterLibFunc(fptr any, handle uintptr, name string) {
@@ -40,7 +50,8 @@ func RegisterLibFunc(fptr any, handle uintptr, name string) {
 // fptr will be set to a function that when called will call the C function given by cfn with the
 // parameters passed in the correct registers and stack.
 //
-// A panic is produced if the type is not a function pointer or if the function returns more than 1 value.
+// A panic is produced if the type is not a function pointer or if the function returns more than 2 values
+// besides a trailing error.
 //
 // These conversions describe how a Go type in the fptr will be used to call
 // the C function. It is important to note that there is no way to verify that fptr
@@ -62,17 +73,59 @@ func RegisterLibFunc(fptr any, handle uintptr, name string) {
 //	int16 <=> int16_t
 //	int32 <=> int32_t
 //	int64 <=> int64_t
+//	CChar <=> char
+//	CInt <=> int
+//	CUInt <=> unsigned int
+//	CLong <=> long
+//	CULong <=> unsigned long
+//	CSizeT <=> size_t
+//	CWChar <=> wchar_t
 //	float32 <=> float
 //	float64 <=> double
-//	struct <=> struct (WIP - darwin only)
+//	complex64 <=> float _Complex (darwin and linux amd64 & arm64)
+//	complex128 <=> double _Complex (darwin and linux amd64 & arm64)
+//	Int128 <=> __int128 (darwin and linux amd64 & arm64)
+//	Uint128 <=> unsigned __int128 (darwin and linux amd64 & arm64)
+//	LongDouble <=> long double (darwin and linux amd64)
+//	struct <=> struct (WIP - darwin and linux amd64 & arm64)
 //	func <=> C function
 //	unsafe.Pointer, *T <=> void*
 //	[]T => void*
+//	[]string => char** (NULL-terminated)
+//	*string <=> char** (out-parameter)
+//	Out[T] <=> T* (out-parameter)
+//	Owned[T, F] <= T (freed with F)
 //
 // There is a special case when the last argument of fptr is a variadic interface (or []interface}
 // it will be expanded into a call to the C function as if it had the arguments in that slice.
 // This means that using arg ...any is like a cast to the function with the arguments inside arg.
-// This is not the same as C variadic.
+// This is not the same as C variadic. To call a C variadic function declare the last argument
+// as ...CVarArg instead. The arguments are then laid out following the rules for variadic arguments
+// of the platform's C calling convention.
+//
+// An argument whose type implements CMarshaler and a result whose pointer type implements CUnmarshaler are
+// converted by those methods instead of by their kind.
+//
+// # Multiple Results
+//
+// On darwin and linux amd64 & arm64 a function may return two integers, pointers, bools or floats.
+// They are read as if the C function returned a struct with two fields of those types, which fits into
+// the two return registers. This covers C functions returning ldiv_t, {ptr, len} pairs or error-code/value pairs
+// without declaring a struct:
+//
+//	// ldiv_t ldiv(long x, long y);
+//	var ldiv func(x, y int64) (quot, rem int64)
+//
+// # Errno
+//
+// The last result of fptr may be of type error or [syscall.Errno]. It is then set from the C errno of the
+// calling thread which is cleared right before and read right after the call to the C function.
+// An error result is nil when errno is zero. Keep in mind that many C functions only set errno on failure
+// and that a successful call may leave a stale value behind, so check the C result first.
+// Functions without such a result leave errno untouched.
+//
+//	var closeFd func(fd int32) (int32, error)
+//	if closeFd(-1) == -1 { ... } // the error is syscall.EBADF
 //
 // # Memory
 //
@@ -93,15 +146,38 @@ func RegisterLibFunc(fptr any, handle uintptr, name string) {
 // using unsafe.Slice. Doing this means that it becomes the responsibility of the caller to care about the lifetime
 // of the pointer
 //
+// A []string argument is passed as a NULL-terminated array of C strings like argv that is only valid for
+// the call. A *string argument is passed as a pointer to a char* which initially points to a copy of the string.
+// After the call the string that the char* points to is copied back into the Go string. It is not freed.
+// A nil []string or *string is passed as NULL.
+//
 // # Structs
 //
-// Purego can handle the most common structs that have fields of builtin types like int8, uint16, float32, etc. However,
-// it does not support aligning fields properly. It is therefore the responsibility of the caller to ensure
+// Purego can handle the most common structs that have fields of builtin types like int8, uint16, float32, etc.
+// By default the fields are laid out like Go does which matches C for naturally aligned fields. When the C struct
+// has a different layout it can be described with a `c` tag on the fields and the Packed marker:
+//
+//   - `c:"offset=N"` places the field at byte offset N which must not overlap the previous field.
+//   - `c:"align=N"` aligns the field to N bytes like _Alignas(N). N must be a power of two.
+//   - A field of type Packed lays out the struct without padding like #pragma pack(1).
+//   - A field of type Union makes the other fields members of a C union that all start at offset 0.
+//
+// Purego then copies the struct into its C layout before passing it and back when it is returned. Fields
+// that end up unaligned must not contain pointers. Otherwise it is the responsibility of the caller to ensure
 // that all padding is added to the Go struct to match the C one. See `BoolStructFn` in struct_test.go for an example.
 //
+// Fields may also be bools, nested structs and arrays of them. A string field is passed as a char* to a copy
+// of the string that is valid for the duration of the call and a func field is passed as a C function pointer
+// created with NewCallback. When a struct is returned these are converted back like string and func results.
+//
 // On Darwin ARM64, purego handles proper alignment of struct arguments when passing them on the stack,
 // following the C ABI's byte-level packing rules.
 //
+// On AMD64, structs are classified per eightbyte following the System V ABI. Structs of up to 16 bytes
+// are passed in integer and floating-point registers when enough are left, otherwise they are copied
+// onto the stack. Structs larger than 16 bytes are always passed in memory and returned through a hidden
+// pointer passed as the first integer argument.
+//
 // # Example
 //
 // All functions below call this C function:
@@ -125,9 +201,6 @@ func RegisterFunc(fptr any, cfn uintptr) {
 	if ty.Kind() != reflect.Func {
 		panic("purego: fptr must be a function pointer")
 	}
-	if ty.NumOut() > 1 {
-		panic("purego: function can only return zero or one values")
-	}
 	if cfn == 0 {
 		panic("purego: cfn is nil")
 	}
@@ -135,7 +208,47 @@ func RegisterFunc(fptr any, cfn uintptr) {
 	if tryRegisterTyped(fptr, cfn) {
 		return
 	}
-	if ty.NumOut() == 1 && (ty.Out(0).Kind() == reflect.Float32 || ty.Out(0).Kind() == reflect.Float64) &&
+	f := newCFunc(ty, cfn)
+	fn.Set(reflect.MakeFunc(ty, f.call))
+}
+
+// cFunc calls the C function cfn with the arguments of a Go function of type ty.
+type cFunc struct {
+	ty         reflect.Type
+	cfn        uintptr
+	numOut     int          // the number of results without errno
+	errnoOut   bool         // the last result receives errno
+	pairOut    reflect.Type // the struct that is returned in place of two results
+	marshalIn  bool         // some arguments are CMarshalers
+	marshalOut bool         // the result is a CUnmarshaler
+	cVariadic  bool         // the last parameter is ...CVarArg
+	isCallback bool         // cfn is a purego callback
+}
+
+// newCFunc checks that a function of type ty can call a C function and panics if it can't.
+func newCFunc(ty reflect.Type, cfn uintptr) *cFunc {
+	errnoOut := hasErrnoResult(ty)
+	numOut := ty.NumOut()
+	if errnoOut {
+		numOut--
+	}
+	marshalOut := numOut == 1 && isCUnmarshaler(ty.Out(0))
+	if marshalOut {
+		if c, ok := reflect.New(ty.Out(0)).Interface().(resultChecker); ok {
+			c.checkResult()
+		}
+	}
+	var marshalIn bool
+	var pairOut reflect.Type
+	switch {
+	case numOut == 2:
+		checkPairResult(ty.Out(0))
+		checkPairResult(ty.Out(1))
+		pairOut = reflect.StructOf([]reflect.StructField{{Name: "R0", Type: ty.Out(0)}, {Name: "R1", Type: ty.Out(1)}})
+	case numOut > 2:
+		panic("purego: function can only return up to two values")
+	}
+	if numOut == 1 && (ty.Out(0).Kind() == reflect.Float32 || ty.Out(0).Kind() == reflect.Float64) &&
 		runtime.GOARCH != "arm64" && runtime.GOARCH != "amd64" && runtime.GOARCH != "loong64" {
 		panic("purego: float returns are not supported")
 	}
@@ -147,6 +260,34 @@ func RegisterFunc(fptr any, cfn uintptr) {
 		var stack int
 		for i := 0; i < ty.NumIn(); i++ {
 			arg := ty.In(i)
+			if i == ty.NumIn()-1 && isCVariadic(ty) {
+				// the variadic arguments are only known when the function is called
+				continue
+			}
+			if isOut(arg) {
+				checkOut(arg)
+				// an out-parameter is passed as a pointer
+				arg = reflect.TypeOf(uintptr(0))
+			}
+			if isCMarshaler(arg) {
+				marshalIn = true
+				numInts, numFloats := numMarshaledValues(arg)
+				for j := 0; j < numInts; j++ {
+					if ints < numOfIntegerRegisters() {
+						ints++
+					} else {
+						stack++
+					}
+				}
+				for j := 0; j < numFloats; j++ {
+					if floats < numOfFloatRegisters {
+						floats++
+					} else {
+						stack++
+					}
+				}
+				continue
+			}
 			switch arg.Kind() {
 			case reflect.Func:
 				// This only does preliminary testing to ensure the CDecl argument
@@ -180,9 +321,11 @@ func RegisterFunc(fptr any, cfn uintptr) {
 					stack++
 				}
 			case reflect.Struct:
-				if runtime.GOOS != "darwin" || (runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64") {
-					panic("purego: struct arguments are only supported on darwin amd64 & arm64")
+				if !structsSupported() {
+					panic("purego: struct arguments are only supported on darwin and linux amd64 & arm64")
 				}
+				arg = cStructType(arg)
+				checkLongDouble(arg)
 				if arg.Size() == 0 {
 					continue
 				}
@@ -196,111 +339,172 @@ func RegisterFunc(fptr any, cfn uintptr) {
 					stack++
 				}
 				_ = addStruct(reflect.New(arg).Elem(), &ints, &floats, &stack, addInt, addFloat, addStack, nil)
+			case reflect.Complex64, reflect.Complex128:
+				if !structsSupported() {
+					panic("purego: complex arguments are only supported on darwin and linux amd64 & arm64")
+				}
+				addInt := func(u uintptr) {
+					ints++
+				}
+				addFloat := func(u uintptr) {
+					floats++
+				}
+				addStack := func(u uintptr) {
+					stack++
+				}
+				_ = addStruct(reflect.New(complexPartsType(arg)).Elem(), &ints, &floats, &stack, addInt, addFloat, addStack, nil)
 			default:
 				panic("purego: unsupported kind " + arg.Kind().String())
 			}
 		}
-		if ty.NumOut() == 1 && ty.Out(0).Kind() == reflect.Struct {
-			if runtime.GOOS != "darwin" {
-				panic("purego: struct return values only supported on darwin arm64 & amd64")
+		if numOut == 1 && ty.Out(0).Kind() == reflect.Struct && !marshalOut {
+			if !structsSupported() {
+				panic("purego: struct return values only supported on darwin and linux amd64 & arm64")
 			}
-			outType := ty.Out(0)
+			outType := cStructType(ty.Out(0))
 			checkStructFieldsSupported(outType)
-			if runtime.GOARCH == "amd64" && outType.Size() > maxRegAllocStructSize {
-				// on amd64 if struct is bigger than 16 bytes allocate the return struct
+			checkLongDouble(outType)
+			if runtime.GOARCH == "amd64" && (outType.Size() > maxRegAllocStructSize || hasUnalignedFields(outType)) {
+				// on amd64 if struct is bigger than 16 bytes or has unaligned fields allocate the return struct
 				// and pass it in as a hidden first argument.
 				ints++
 			}
 		}
 
-		sizeOfStack := maxArgs - numOfIntegerRegisters()
-		// On Darwin ARM64, use byte-based validation since arguments pack efficiently
-		if runtime.GOOS == "darwin" && runtime.GOARCH == "arm64" {
-			stackBytes := estimateStackBytes(ty)
-			maxStackBytes := sizeOfStack * 8
-			if stackBytes > maxStackBytes {
-				panic("purego: too many stack arguments")
-			}
-		} else {
-			if stack > sizeOfStack {
-				panic("purego: too many stack arguments")
+		if numOut == 1 && !marshalOut {
+			switch ty.Out(0).Kind() {
+			case reflect.Uintptr, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+				reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Bool,
+				reflect.UnsafePointer, reflect.Ptr, reflect.Func, reflect.String, reflect.Float32, reflect.Float64,
+				reflect.Complex64, reflect.Complex128, reflect.Struct:
+			default:
+				panic("purego: unsupported return kind: " + ty.Out(0).Kind().String())
 			}
 		}
+		if numOut == 1 && (ty.Out(0).Kind() == reflect.Complex64 || ty.Out(0).Kind() == reflect.Complex128) && !marshalOut && !structsSupported() {
+			panic("purego: complex return values only supported on darwin and linux amd64 & arm64")
+		}
+
+		// the stack is only limited on platforms that can't grow the stack area of syscall15X
+		if sizeOfStack := maxArgs - numOfIntegerRegisters(); !stackArgsUnlimited() && stack > sizeOfStack {
+			panic("purego: too many stack arguments")
+		}
 	}
 
-	// Detect if cfn is a callback (to avoid tight packing for callbacks which still use 8-byte slots)
-	// TODO: Remove this check once Darwin ARM64 callback unpacking is updated to handle C-style tight packing.
-	// When callbacks can unpack tightly-packed arguments, this workaround can be removed.
-	isCallback := isCallbackFunction(cfn)
-
-	v := reflect.MakeFunc(ty, func(args []reflect.Value) (results []reflect.Value) {
-		var sysargs [maxArgs]uintptr
-		var floats [numOfFloatRegisters]uintptr
-		var numInts int
-		var numFloats int
-		var numStack int
-		var addStack, addInt, addFloat func(x uintptr)
-		if runtime.GOARCH == "arm64" || runtime.GOOS != "windows" {
-			// Windows arm64 uses the same calling convention as macOS and Linux
-			addStack = func(x uintptr) {
-				sysargs[numOfIntegerRegisters()+numStack] = x
-				numStack++
-			}
-			addInt = func(x uintptr) {
-				if numInts >= numOfIntegerRegisters() {
-					addStack(x)
-				} else {
-					sysargs[numInts] = x
-					numInts++
-				}
+	return &cFunc{
+		ty:         ty,
+		cfn:        cfn,
+		numOut:     numOut,
+		errnoOut:   errnoOut,
+		pairOut:    pairOut,
+		marshalIn:  marshalIn,
+		marshalOut: marshalOut,
+		// Detect if cfn is a callback (to avoid tight packing for callbacks which still use 8-byte slots)
+		// TODO: Remove this check once Darwin ARM64 callback unpacking is updated to handle C-style tight packing.
+		// When callbacks can unpack tightly-packed arguments, this workaround can be removed.
+		isCallback: isCallbackFunction(cfn),
+		cVariadic:  isCVariadic(ty),
+	}
+}
+
+// call calls the C function with args and returns the results converted to the result types of ty.
+func (f *cFunc) call(args []reflect.Value) (results []reflect.Value) {
+	ty := f.ty
+	var sysargs [maxArgs]uintptr
+	var stackArgs []uintptr // stack arguments that don't fit into sysargs
+	var floats [numOfFloatRegisters]uintptr
+	var numInts int
+	var numFloats int
+	var numStack int
+	var addStack, addInt, addFloat func(x uintptr)
+	if runtime.GOARCH == "arm64" || runtime.GOOS != "windows" {
+		// Windows arm64 uses the same calling convention as macOS and Linux
+		addStack = func(x uintptr) {
+			if i := numOfIntegerRegisters() + numStack; i < len(sysargs) {
+				sysargs[i] = x
+			} else {
+				stackArgs = append(stackArgs, x)
 			}
-			addFloat = func(x uintptr) {
-				if numFloats < len(floats) {
-					floats[numFloats] = x
-					numFloats++
-				} else {
-					addStack(x)
-				}
+			numStack++
+		}
+		addInt = func(x uintptr) {
+			if numInts >= numOfIntegerRegisters() {
+				addStack(x)
+			} else {
+				sysargs[numInts] = x
+				numInts++
 			}
-		} else {
-			// On Windows amd64 the arguments are passed in the numbered registered.
-			// So the first int is in the first integer register and the first float
-			// is in the second floating register if there is already a first int.
-			// This is in contrast to how macOS and Linux pass arguments which
-			// tries to use as many registers as possible in the calling convention.
-			addStack = func(x uintptr) {
-				sysargs[numStack] = x
-				numStack++
+		}
+		addFloat = func(x uintptr) {
+			if numFloats < len(floats) {
+				floats[numFloats] = x
+				numFloats++
+			} else {
+				addStack(x)
 			}
-			addInt = addStack
-			addFloat = addStack
 		}
+	} else {
+		// On Windows amd64 the arguments are passed in the numbered registered.
+		// So the first int is in the first integer register and the first float
+		// is in the second floating register if there is already a first int.
+		// This is in contrast to how macOS and Linux pass arguments which
+		// tries to use as many registers as possible in the calling convention.
+		addStack = func(x uintptr) {
+			sysargs[numStack] = x
+			numStack++
+		}
+		addInt = addStack
+		addFloat = addStack
+	}
 
-		var keepAlive []any
-		defer func() {
-			runtime.KeepAlive(keepAlive)
-			runtime.KeepAlive(args)
-		}()
+	var keepAlive []any
+	defer func() {
+		unpinOutParams(keepAlive)
+		runtime.KeepAlive(keepAlive)
+		runtime.KeepAlive(args)
+	}()
 
-		var arm64_r8 uintptr
-		if ty.NumOut() == 1 && ty.Out(0).Kind() == reflect.Struct {
-			outType := ty.Out(0)
-			if (runtime.GOARCH == "amd64" || runtime.GOARCH == "loong64") && outType.Size() > maxRegAllocStructSize {
+	var arm64_r8, amd64_x87 uintptr
+	if f.numOut == 1 && ty.Out(0).Kind() == reflect.Struct && !f.marshalOut {
+		outType := cStructType(ty.Out(0))
+		if returnsLongDouble(outType) {
+			amd64_x87 = 1
+		}
+		if runtime.GOARCH == "amd64" && hasUnalignedFields(outType) || (runtime.GOARCH == "amd64" || runtime.GOARCH == "loong64") && outType.Size() > maxRegAllocStructSize {
+			val := reflect.New(outType)
+			keepAlive = append(keepAlive, val)
+			addInt(val.Pointer())
+		} else if runtime.GOARCH == "arm64" && outType.Size() > maxRegAllocStructSize {
+			if _, _, hfa := hfaMembers(outType); !hfa {
 				val := reflect.New(outType)
 				keepAlive = append(keepAlive, val)
-				addInt(val.Pointer())
-			} else if runtime.GOARCH == "arm64" && outType.Size() > maxRegAllocStructSize {
-				isAllFloats, numFields := isAllSameFloat(outType)
-				if !isAllFloats || numFields > 4 {
-					val := reflect.New(outType)
-					keepAlive = append(keepAlive, val)
-					arm64_r8 = val.Pointer()
-				}
+				arm64_r8 = val.Pointer()
 			}
 		}
-		for i, v := range args {
-			if variadic, ok := xreflect.TypeAssert[[]any](args[i]); ok {
-				if i != len(args)-1 {
+	}
+	fixedArgs := args
+	var varArgs []CVarArg
+	if f.cVariadic {
+		fixedArgs = args[:len(args)-1]
+		varArgs, _ = xreflect.TypeAssert[[]CVarArg](args[len(args)-1])
+	}
+	if f.marshalIn && runtime.GOARCH == "arm64" && runtime.GOOS == "darwin" && !f.isCallback {
+		// the stack arguments are packed by their type so the values of a CMarshaler must be separate arguments
+		fixedArgs = expandCMarshalers(fixedArgs)
+	}
+	for i, v := range fixedArgs {
+		// copy structs with a C layout into their C memory layout before placing any argument
+		if v.Kind() == reflect.Struct && !(f.marshalIn && isCMarshaler(v.Type())) {
+			if l := structLayoutOf(v.Type()); l != nil {
+				fixedArgs[i] = l.toC(v)
+			}
+		}
+	}
+	for i, v := range fixedArgs {
+		// checking the kind first keeps the type assertion off the path of other arguments
+		if v.Kind() == reflect.Slice {
+			if variadic, ok := xreflect.TypeAssert[[]any](v); ok {
+				if i != len(fixedArgs)-1 {
 					panic("purego: can only expand last parameter")
 				}
 				for _, x := range variadic {
@@ -308,102 +512,199 @@ func RegisterFunc(fptr any, cfn uintptr) {
 				}
 				continue
 			}
-			// Check if we need to start Darwin ARM64 C-style stack packing
-			// Skip tight packing for callbacks since they still use 8-byte slot unpacking
-			// TODO: Remove !isCallback condition once callback unpacking supports tight packing
-			if runtime.GOARCH == "arm64" && runtime.GOOS == "darwin" && !isCallback && shouldBundleStackArgs(v, numInts, numFloats) {
-				// Collect and separate remaining args into register vs stack
-				stackArgs, newKeepAlive := collectStackArgs(args, i, numInts, numFloats,
-					keepAlive, addInt, addFloat, addStack, &numInts, &numFloats, &numStack)
-				keepAlive = newKeepAlive
-
-				// Bundle stack arguments with C-style packing
-				bundleStackArgs(stackArgs, addStack)
-				break
-			}
-			keepAlive = addValue(v, keepAlive, addInt, addFloat, addStack, &numInts, &numFloats, &numStack)
 		}
+		// Check if we need to start Darwin ARM64 C-style stack packing
+		// Skip tight packing for callbacks since they still use 8-byte slot unpacking
+		// TODO: Remove !f.isCallback condition once callback unpacking supports tight packing
+		if runtime.GOARCH == "arm64" && runtime.GOOS == "darwin" && !f.isCallback && shouldBundleStackArgs(v, numInts, numFloats) {
+			// Collect and separate remaining args into register vs stack
+			stackArgs, newKeepAlive := collectStackArgs(fixedArgs, i, numInts, numFloats,
+				keepAlive, addInt, addFloat, addStack, &numInts, &numFloats, &numStack)
+			keepAlive = newKeepAlive
 
-		syscall := thePool.Get().(*syscall15Args)
-		defer thePool.Put(syscall)
+			// Bundle stack arguments with C-style packing
+			bundleStackArgs(stackArgs, addStack)
+			break
+		}
+		keepAlive = addValue(v, keepAlive, addInt, addFloat, addStack, &numInts, &numFloats, &numStack)
+	}
+	for _, x := range varArgs {
+		keepAlive = addCVarArg(reflect.ValueOf(x), keepAlive, addInt, addFloat, addStack, &numInts, &numFloats, &numStack)
+	}
 
-		if runtime.GOARCH == "loong64" {
-			*syscall = syscall15Args{
-				cfn,
-				sysargs[0], sysargs[1], sysargs[2], sysargs[3], sysargs[4], sysargs[5],
-				sysargs[6], sysargs[7], sysargs[8], sysargs[9], sysargs[10], sysargs[11],
-				sysargs[12], sysargs[13], sysargs[14],
-				floats[0], floats[1], floats[2], floats[3], floats[4], floats[5], floats[6], floats[7],
-				0,
-			}
-			runtime_cgocall(syscall15XABI0, unsafe.Pointer(syscall))
-		} else if runtime.GOARCH == "arm64" || runtime.GOOS != "windows" {
-			// Use the normal arm64 calling convention even on Windows
-			*syscall = syscall15Args{
-				cfn,
-				sysargs[0], sysargs[1], sysargs[2], sysargs[3], sysargs[4], sysargs[5],
-				sysargs[6], sysargs[7], sysargs[8], sysargs[9], sysargs[10], sysargs[11],
-				sysargs[12], sysargs[13], sysargs[14],
-				floats[0], floats[1], floats[2], floats[3], floats[4], floats[5], floats[6], floats[7],
-				arm64_r8,
-			}
-			runtime_cgocall(syscall15XABI0, unsafe.Pointer(syscall))
-		} else {
-			*syscall = syscall15Args{}
-			// This is a fallback for Windows amd64, 386, and arm. Note this may not support floats
-			syscall.a1, syscall.a2, _ = syscall_syscall15X(cfn, sysargs[0], sysargs[1], sysargs[2], sysargs[3], sysargs[4],
-				sysargs[5], sysargs[6], sysargs[7], sysargs[8], sysargs[9], sysargs[10], sysargs[11],
-				sysargs[12], sysargs[13], sysargs[14])
-			syscall.f1 = syscall.a2 // on amd64 a2 stores the float return. On 32bit platforms floats aren't support
-		}
-		if ty.NumOut() == 0 {
-			return nil
-		}
-		outType := ty.Out(0)
-		v := reflect.New(outType).Elem()
-		switch outType.Kind() {
-		case reflect.Uintptr, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			v.SetUint(uint64(syscall.a1))
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			v.SetInt(int64(syscall.a1))
-		case reflect.Bool:
-			v.SetBool(byte(syscall.a1) != 0)
-		case reflect.UnsafePointer:
-			// We take the address and then dereference it to trick go vet from creating a possible miss-use of unsafe.Pointer
-			v.SetPointer(*(*unsafe.Pointer)(unsafe.Pointer(&syscall.a1)))
-		case reflect.Ptr:
-			v = reflect.NewAt(outType, unsafe.Pointer(&syscall.a1)).Elem()
-		case reflect.Func:
-			// wrap this C function in a nicely typed Go function
-			v = reflect.New(outType)
-			RegisterFunc(v.Interface(), syscall.a1)
-		case reflect.String:
-			v.SetString(strings.GoString(syscall.a1))
-		case reflect.Float32:
-			// NOTE: syscall.r2 is only the floating return value on 64bit platforms.
-			// On 32bit platforms syscall.r2 is the upper part of a 64bit return.
-			v.SetFloat(float64(math.Float32frombits(uint32(syscall.f1))))
-		case reflect.Float64:
-			// NOTE: syscall.r2 is only the floating return value on 64bit platforms.
-			// On 32bit platforms syscall.r2 is the upper part of a 64bit return.
-			v.SetFloat(math.Float64frombits(uint64(syscall.f1)))
-		case reflect.Struct:
-			v = getStruct(outType, *syscall)
-		default:
-			panic("purego: unsupported return kind: " + outType.Kind().String())
+	syscall := thePool.Get().(*syscall15Args)
+	defer putSyscall(syscall)
+	f.invoke(syscall, &sysargs, &floats, stackArgs, arm64_r8, amd64_x87)
+	copyOutParams(keepAlive)
+	return f.results(args, syscall)
+}
+
+// invoke calls the C function with the placed arguments and stores its results in syscall.
+func (f *cFunc) invoke(syscall *syscall15Args, sysargs *[maxArgs]uintptr, floats *[numOfFloatRegisters]uintptr, stackArgs []uintptr, arm64_r8, amd64_x87 uintptr) {
+	cfn := f.cfn
+	var stackArgsPtr uintptr
+	if len(stackArgs) > 0 {
+		stackArgsPtr = uintptr(unsafe.Pointer(&stackArgs[0]))
+	}
+	// errno is only read when the function has an error result
+	var captureErrno uintptr
+	if f.errnoOut {
+		captureErrno = 1
+	}
+	if runtime.GOARCH == "loong64" {
+		*syscall = syscall15Args{
+			cfn,
+			sysargs[0], sysargs[1], sysargs[2], sysargs[3], sysargs[4], sysargs[5],
+			sysargs[6], sysargs[7], sysargs[8], sysargs[9], sysargs[10], sysargs[11],
+			sysargs[12], sysargs[13], sysargs[14],
+			floats[0], floats[1], floats[2], floats[3], floats[4], floats[5], floats[6], floats[7],
+			0, 0, stackArgsPtr, uintptr(len(stackArgs)), 0, captureErrno,
 		}
-		if len(args) > 0 {
-			// reuse args slice instead of allocating one when possible
-			args[0] = v
-			return args[:1]
+		runtime_cgocall(syscall15XABI0, unsafe.Pointer(syscall))
+	} else if runtime.GOARCH == "arm64" || runtime.GOOS != "windows" {
+		// Use the normal arm64 calling convention even on Windows
+		*syscall = syscall15Args{
+			cfn,
+			sysargs[0], sysargs[1], sysargs[2], sysargs[3], sysargs[4], sysargs[5],
+			sysargs[6], sysargs[7], sysargs[8], sysargs[9], sysargs[10], sysargs[11],
+			sysargs[12], sysargs[13], sysargs[14],
+			floats[0], floats[1], floats[2], floats[3], floats[4], floats[5], floats[6], floats[7],
+			arm64_r8, 0, stackArgsPtr, uintptr(len(stackArgs)), amd64_x87, captureErrno,
+		}
+		runtime_cgocall(syscall15XABI0, unsafe.Pointer(syscall))
+	} else {
+		*syscall = syscall15Args{}
+		// This is a fallback for Windows amd64, 386, and arm. Note this may not support floats
+		syscall.a1, syscall.a2, syscall.err = syscall_syscall15X(cfn, sysargs[0], sysargs[1], sysargs[2], sysargs[3], sysargs[4],
+			sysargs[5], sysargs[6], sysargs[7], sysargs[8], sysargs[9], sysargs[10], sysargs[11],
+			sysargs[12], sysargs[13], sysargs[14])
+		syscall.f1 = syscall.a2 // on amd64 a2 stores the float return. On 32bit platforms floats aren't support
+	}
+	runtime.KeepAlive(stackArgs)
+}
+
+// results converts the return values of the C function in syscall to the result types of f.
+// It reuses args for the results when possible.
+func (f *cFunc) results(args []reflect.Value, syscall *syscall15Args) []reflect.Value {
+	ty := f.ty
+	if f.pairOut != nil {
+		v := getStruct(f.pairOut, *syscall)
+		if f.errnoOut {
+			return []reflect.Value{v.Field(0), v.Field(1), errnoValue(ty.Out(2), syscall.err)}
+		}
+		return []reflect.Value{v.Field(0), v.Field(1)}
+	}
+	if f.numOut == 0 {
+		if f.errnoOut {
+			return []reflect.Value{errnoValue(ty.Out(0), syscall.err)}
+		}
+		return nil
+	}
+	outType := ty.Out(0)
+	if f.marshalOut {
+		ints := []uintptr{syscall.a1, syscall.a2}
+		floats := []float64{math.Float64frombits(uint64(syscall.f1)), math.Float64frombits(uint64(syscall.f2))}
+		return f.result(args, unmarshalC(outType, ints, floats), syscall)
+	}
+	v := reflect.New(outType).Elem()
+	switch outType.Kind() {
+	case reflect.Uintptr, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(uint64(syscall.a1))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(syscall.a1))
+	case reflect.Bool:
+		v.SetBool(byte(syscall.a1) != 0)
+	case reflect.UnsafePointer:
+		// We take the address and then dereference it to trick go vet from creating a possible miss-use of unsafe.Pointer
+		v.SetPointer(*(*unsafe.Pointer)(unsafe.Pointer(&syscall.a1)))
+	case reflect.Ptr:
+		// copy the pointer out since syscall is cleared when it goes back to the pool
+		v.Set(reflect.NewAt(outType, unsafe.Pointer(&syscall.a1)).Elem())
+	case reflect.Func:
+		// wrap this C function in a nicely typed Go function
+		v = reflect.New(outType)
+		RegisterFunc(v.Interface(), syscall.a1)
+	case reflect.String:
+		v.SetString(strings.GoString(syscall.a1))
+	case reflect.Float32:
+		// NOTE: syscall.r2 is only the floating return value on 64bit platforms.
+		// On 32bit platforms syscall.r2 is the upper part of a 64bit return.
+		v.SetFloat(float64(math.Float32frombits(uint32(syscall.f1))))
+	case reflect.Float64:
+		// NOTE: syscall.r2 is only the floating return value on 64bit platforms.
+		// On 32bit platforms syscall.r2 is the upper part of a 64bit return.
+		v.SetFloat(math.Float64frombits(uint64(syscall.f1)))
+	case reflect.Complex64, reflect.Complex128:
+		setComplex(v, getStruct(complexPartsType(outType), *syscall))
+	case reflect.Struct:
+		if l := structLayoutOf(outType); l != nil {
+			v = l.fromC(getStruct(l.ctype, *syscall), outType)
 		} else {
-			return []reflect.Value{v}
+			v = getStruct(outType, *syscall)
 		}
-	})
-	fn.Set(v)
+	default:
+		panic("purego: unsupported return kind: " + outType.Kind().String())
+	}
+	return f.result(args, v, syscall)
+}
+
+// result returns the result v and errno if f has an errno result. It reuses args when possible.
+func (f *cFunc) result(args []reflect.Value, v reflect.Value, syscall *syscall15Args) []reflect.Value {
+	if f.errnoOut {
+		return []reflect.Value{v, errnoValue(f.ty.Out(1), syscall.err)}
+	}
+	if len(args) > 0 {
+		// reuse args slice instead of allocating one when possible
+		args[0] = v
+		return args[:1]
+	} else {
+		return []reflect.Value{v}
+	}
+}
+
+var (
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+	errnoType = reflect.TypeOf(syscall.Errno(0))
+)
+
+// checkPairResult panics if t can't be one of two results of a function.
+func checkPairResult(t reflect.Type) {
+	switch t.Kind() {
+	case reflect.Uintptr, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Bool,
+		reflect.UnsafePointer, reflect.Ptr, reflect.Float32, reflect.Float64:
+	default:
+		panic("purego: unsupported kind of two results: " + t.Kind().String())
+	}
+	if !structsSupported() {
+		panic("purego: two results are only supported on darwin and linux amd64 & arm64")
+	}
+}
+
+// hasErrnoResult reports whether the last result of the function type ty receives errno.
+func hasErrnoResult(ty reflect.Type) bool {
+	if ty.NumOut() == 0 {
+		return false
+	}
+	out := ty.Out(ty.NumOut() - 1)
+	return out == errorType || out == errnoType
+}
+
+// errnoValue returns errno as a value of type t which is either error or syscall.Errno.
+// A zero errno becomes a nil error.
+func errnoValue(t reflect.Type, errno uintptr) reflect.Value {
+	v := reflect.New(t).Elem()
+	if errno != 0 {
+		v.Set(reflect.ValueOf(syscall.Errno(errno)))
+	}
+	return v
 }
 
 func addValue(v reflect.Value, keepAlive []any, addInt func(x uintptr), addFloat func(x uintptr), addStack func(x uintptr), numInts *int, numFloats *int, numStack *int) []any {
+	if v.IsValid() && isCMarshaler(v.Type()) {
+		addCMarshaler(v, addInt, addFloat)
+		return keepAlive
+	}
 	switch v.Kind() {
 	case reflect.String:
 		ptr := strings.CString(v.String())
@@ -413,9 +714,17 @@ func addValue(v reflect.Value, keepAlive []any, addInt func(x uintptr), addFloat
 		addInt(uintptr(v.Uint()))
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		addInt(uintptr(v.Int()))
-	case reflect.Ptr, reflect.UnsafePointer, reflect.Slice:
+	case reflect.Ptr, reflect.Slice:
+		if isCStrings(v.Type()) {
+			var ptr unsafe.Pointer
+			ptr, keepAlive = cStrings(v, keepAlive)
+			addInt(uintptr(ptr))
+			break
+		}
 		// There is no need to keepAlive this pointer separately because it is kept alive in the args variable
 		addInt(v.Pointer())
+	case reflect.UnsafePointer:
+		addInt(v.Pointer())
 	case reflect.Func:
 		addInt(NewCallback(v.Interface()))
 	case reflect.Bool:
@@ -428,7 +737,20 @@ func addValue(v reflect.Value, keepAlive []any, addInt func(x uintptr), addFloat
 		addFloat(uintptr(math.Float32bits(float32(v.Float()))))
 	case reflect.Float64:
 		addFloat(uintptr(math.Float64bits(v.Float())))
+	case reflect.Complex64, reflect.Complex128:
+		keepAlive = addStruct(complexParts(v), numInts, numFloats, numStack, addInt, addFloat, addStack, keepAlive)
 	case reflect.Struct:
+		if isOut(v.Type()) {
+			var ptr unsafe.Pointer
+			ptr, keepAlive = outArg(v, keepAlive)
+			addInt(uintptr(ptr))
+			break
+		}
+		if l := structLayoutOf(v.Type()); l != nil {
+			v = l.toC(v)
+			// the C struct keeps the C strings of string fields alive
+			keepAlive = append(keepAlive, v)
+		}
 		keepAlive = addStruct(v, numInts, numFloats, numStack, addInt, addFloat, addStack, keepAlive)
 	default:
 		panic("purego: unsupported kind: " + v.Kind().String())
@@ -436,6 +758,88 @@ func addValue(v reflect.Value, keepAlive []any, addInt func(x uintptr), addFloat
 	return keepAlive
 }
 
+// isCStrings reports whether t is a []string that is passed as a NULL-terminated char**
+// or a *string that is passed as a char** out-parameter.
+func isCStrings(t reflect.Type) bool {
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Ptr) && t.Elem().Kind() == reflect.String
+}
+
+// copier is an argument whose C memory is copied back into Go after the call.
+type copier interface {
+	copyBack()
+}
+
+// stringOut is a *string argument that is passed as a char**.
+type stringOut struct {
+	s reflect.Value // the string
+	c *byte         // the char* that the C function may replace
+}
+
+// cStrings returns the char** for v which is a []string or a *string.
+// The C strings are kept alive by the returned keepAlive. For a *string the char* initially points to a copy
+// of the string and the C string it points to after the call is copied back by copyOutParams.
+func cStrings(v reflect.Value, keepAlive []any) (unsafe.Pointer, []any) {
+	if v.IsNil() {
+		return nil, keepAlive
+	}
+	if v.Kind() == reflect.Ptr {
+		out := &stringOut{s: v.Elem(), c: strings.CString(v.Elem().String())}
+		return unsafe.Pointer(&out.c), append(keepAlive, out)
+	}
+	// the last element stays nil to terminate the array
+	array := make([]*byte, v.Len()+1)
+	for i := 0; i < v.Len(); i++ {
+		array[i] = strings.CString(v.Index(i).String())
+	}
+	return unsafe.Pointer(&array[0]), append(keepAlive, array)
+}
+
+// copyBack copies the C string back into the Go string. The C string is not freed.
+func (out *stringOut) copyBack() {
+	out.s.SetString(strings.GoString(uintptr(unsafe.Pointer(out.c))))
+}
+
+// copyOutParams copies the out-parameters in keepAlive back into Go.
+func copyOutParams(keepAlive []any) {
+	for _, x := range keepAlive {
+		if out, ok := x.(copier); ok {
+			out.copyBack()
+		}
+	}
+}
+
+// addCVarArg places a variadic argument of a C variadic function after applying the default argument promotions.
+func addCVarArg(v reflect.Value, keepAlive []any, addInt func(x uintptr), addFloat func(x uintptr), addStack func(x uintptr), numInts *int, numFloats *int, numStack *int) []any {
+	if !v.IsValid() {
+		// a nil interface is passed as NULL
+		addInt(0)
+		return keepAlive
+	}
+	if v.Kind() == reflect.Float32 {
+		v = reflect.ValueOf(v.Float())
+	}
+	switch {
+	case runtime.GOOS == "darwin" && runtime.GOARCH == "arm64":
+		// Apple ARM64 passes every variadic argument on the stack in its own 8-byte slot.
+		if v.Kind() == reflect.Struct {
+			panic("purego: struct variadic arguments are not supported on darwin/arm64")
+		}
+		addInt, addFloat = addStack, addStack
+	case runtime.GOARCH == "loong64":
+		// LoongArch passes variadic floating-point arguments in integer registers.
+		addFloat = addInt
+	}
+	return addValue(v, keepAlive, addInt, addFloat, addStack, numInts, numFloats, numStack)
+}
+
+// cVarArgsType is the type of the last parameter of a function that calls a C variadic function.
+var cVarArgsType = reflect.TypeOf([]CVarArg(nil))
+
+// isCVariadic reports whether the function type ty calls a C variadic function.
+func isCVariadic(ty reflect.Type) bool {
+	return ty.IsVariadic() && ty.In(ty.NumIn()-1) == cVarArgsType
+}
+
 // maxRegAllocStructSize is the biggest a struct can be while still fitting in registers.
 // if it is bigger than this than enough space must be allocated on the heap and then passed into
 // the function as the first parameter on amd64 or in R8 on arm64.
@@ -469,29 +873,96 @@ func isAllSameFloat(ty reflect.Type) (allFloats bool, numFields int) {
 	return allFloats, numFields
 }
 
+// hfaMembers reports whether ty is a Homogeneous Floating-point Aggregate as defined by AAPCS64.
+// That is a struct whose fields, once nested structs and arrays are flattened, are between one and
+// four floating-point values of the same kind. It returns the kind and the number of those members.
+func hfaMembers(ty reflect.Type) (member reflect.Kind, n int, ok bool) {
+	ok = true
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		switch t.Kind() {
+		case reflect.Struct:
+			for i := 0; i < t.NumField() && ok; i++ {
+				walk(t.Field(i).Type)
+			}
+		case reflect.Array:
+			for i := 0; i < t.Len() && ok; i++ {
+				walk(t.Elem())
+			}
+		case reflect.Float32, reflect.Float64:
+			if member != reflect.Invalid && member != t.Kind() {
+				ok = false
+				return
+			}
+			member = t.Kind()
+			n++
+			if n > 4 {
+				ok = false
+			}
+		case reflect.Complex64, reflect.Complex128:
+			// a complex number is a pair of floats
+			walk(complexPartsType(t))
+		default:
+			ok = false
+		}
+	}
+	walk(ty)
+	if !ok || n == 0 {
+		return reflect.Invalid, 0, false
+	}
+	return member, n, true
+}
+
 func checkStructFieldsSupported(ty reflect.Type) {
 	for i := 0; i < ty.NumField(); i++ {
 		f := ty.Field(i).Type
-		if f.Kind() == reflect.Array {
+		for f.Kind() == reflect.Array {
 			f = f.Elem()
-		} else if f.Kind() == reflect.Struct {
+		}
+		if f.Kind() == reflect.Struct {
 			checkStructFieldsSupported(f)
 			continue
 		}
 		switch f.Kind() {
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
-			reflect.Uintptr, reflect.Ptr, reflect.UnsafePointer, reflect.Float64, reflect.Float32:
+			reflect.Uintptr, reflect.Ptr, reflect.UnsafePointer, reflect.Float64, reflect.Float32,
+			reflect.Complex64, reflect.Complex128:
 		default:
 			panic(fmt.Sprintf("purego: struct field type %s is not supported", f))
 		}
 	}
 }
 
+// structsSupported reports whether struct arguments and return values can be
+// passed by value on the current platform.
+func structsSupported() bool {
+	switch runtime.GOOS {
+	case "darwin":
+		return runtime.GOARCH == "amd64" || runtime.GOARCH == "arm64"
+	case "linux":
+		return runtime.GOARCH == "amd64" || runtime.GOARCH == "arm64"
+	}
+	return false
+}
+
 func roundUpTo8(val uintptr) uintptr {
 	return (val + align8ByteMask) &^ align8ByteMask
 }
 
+// stackArgsUnlimited reports whether syscall15X takes an arbitrary number of stack arguments
+// through syscall15Args.stackArgs. Otherwise, only maxArgs arguments can be passed in total.
+func stackArgsUnlimited() bool {
+	switch runtime.GOARCH {
+	case "amd64":
+		return runtime.GOOS != "windows"
+	case "arm64", "loong64":
+		return true
+	default:
+		return false
+	}
+}
+
 func numOfIntegerRegisters() int {
 	switch runtime.GOARCH {
 	case "arm64", "loong64":
@@ -505,34 +976,6 @@ func numOfIntegerRegisters() int {
 	}
 }
 
-// estimateStackBytes estimates stack bytes needed for Darwin ARM64 validation.
-// This is a conservative estimate used only for early error detection.
-func estimateStackBytes(ty reflect.Type) int {
-	numInts, numFloats := 0, 0
-	stackBytes := 0
-
-	for i := 0; i < ty.NumIn(); i++ {
-		arg := ty.In(i)
-		size := int(arg.Size())
-
-		// Check if this goes to register or stack
-		usesInt := arg.Kind() != reflect.Float32 && arg.Kind() != reflect.Float64
-		if usesInt && numInts < numOfIntegerRegisters() {
-			numInts++
-		} else if !usesInt && numFloats < numOfFloatRegisters {
-			numFloats++
-		} else {
-			// Goes to stack - accumulate total bytes
-			stackBytes += size
-		}
-	}
-	// Round total to 8-byte boundary
-	if stackBytes > 0 && stackBytes%align8ByteSize != 0 {
-		stackBytes = int(roundUpTo8(uintptr(stackBytes)))
-	}
-	return stackBytes
-}
-
 // isCallbackFunction checks if the given function pointer is a purego callback.
 // We need to detect this to avoid using tight packing for callbacks, since callback
 // unpacking still uses the 8-byte slot convention.
@@ -11,6 +11,7 @@ import (
 
 // Syscall0 through Syscall15 are specialized versions of SyscallN that avoid
 // variadic overhead and array copying. Zero allocations when the pool is warm.
+// Use SyscallN to call functions with more than 15 arguments.
 
 //go:nosplit
 func Syscall0(fn uintptr) uintptr {
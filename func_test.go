@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"syscall"
 	"testing"
 	"unsafe"
 
@@ -47,6 +48,13 @@ func TestRegisterFunc(t *testing.T) {
 	var puts func(string)
 	purego.RegisterLibFunc(&puts, libc, "puts")
 	puts("Calling C from from Go without Cgo!")
+
+	var strchr func(s *byte, c int32) *byte
+	purego.RegisterLibFunc(&strchr, libc, "strchr")
+	buf := []byte("hello\x00")
+	if got := strchr(&buf[0], 'l'); got != &buf[2] {
+		t.Errorf("strchr failed. got %p but wanted %p", got, &buf[2])
+	}
 }
 
 func Test_qsort(t *testing.T) {
@@ -117,6 +125,227 @@ func TestRegisterFunc_Floats(t *testing.T) {
 	}
 }
 
+func TestRegisterFunc_CVariadic(t *testing.T) {
+	if runtime.GOARCH != "arm64" && runtime.GOARCH != "amd64" && runtime.GOARCH != "loong64" {
+		t.Skip("Platform doesn't support Floats")
+		return
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("snprintf is not exported by ucrtbase.dll")
+	}
+	library, err := getSystemLibrary()
+	if err != nil {
+		t.Fatalf("couldn't get system library: %s", err)
+	}
+	libc, err := load.OpenLibrary(library)
+	if err != nil {
+		t.Fatalf("failed to dlopen: %s", err)
+	}
+	var snprintf func(buf []byte, size uintptr, format string, args ...purego.CVarArg) int32
+	purego.RegisterLibFunc(&snprintf, libc, "snprintf")
+	{
+		buf := make([]byte, 128)
+		n := snprintf(buf, uintptr(len(buf)), "%d %s %.2f %.1f %lld", int32(-5), "go", 3.25, float32(1.5), int64(1)<<40)
+		const want = "-5 go 3.25 1.5 1099511627776"
+		if got := string(buf[:n]); got != want {
+			t.Errorf("snprintf failed. got %q but wanted %q", got, want)
+		}
+	}
+	{
+		buf := make([]byte, 128)
+		const want = "no args"
+		n := snprintf(buf, uintptr(len(buf)), want)
+		if got := string(buf[:n]); got != want {
+			t.Errorf("snprintf failed. got %q but wanted %q", got, want)
+		}
+	}
+	{
+		buf := make([]byte, 128)
+		n := snprintf(buf, uintptr(len(buf)), "%d:%.1f:%d:%.1f", 1, 2.0, 3, float32(4))
+		const want = "1:2.0:3:4.0"
+		if got := string(buf[:n]); got != want {
+			t.Errorf("snprintf failed. got %q but wanted %q", got, want)
+		}
+	}
+}
+
+func TestRegisterFunc_Errno(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("errno is only captured on unix platforms")
+	}
+	library, err := getSystemLibrary()
+	if err != nil {
+		t.Fatalf("couldn't get system library: %s", err)
+	}
+	libc, err := load.OpenLibrary(library)
+	if err != nil {
+		t.Fatalf("failed to dlopen: %s", err)
+	}
+	var closeFd func(fd int32) (int32, error)
+	purego.RegisterLibFunc(&closeFd, libc, "close")
+	if r, err := closeFd(-1); r != -1 || !errors.Is(err, syscall.EBADF) {
+		t.Errorf("close(-1) failed. got (%d, %v) but wanted (-1, %v)", r, err, syscall.EBADF)
+	}
+	var getpid func() (int32, error)
+	purego.RegisterLibFunc(&getpid, libc, "getpid")
+	if r, err := getpid(); r <= 0 || err != nil {
+		t.Errorf("getpid failed. got (%d, %v) but wanted a pid and a nil error", r, err)
+	}
+	var closeErrno func(fd int32) syscall.Errno
+	purego.RegisterLibFunc(&closeErrno, libc, "close")
+	if errno := closeErrno(-1); errno != syscall.EBADF {
+		t.Errorf("close(-1) failed. got %v but wanted %v", errno, syscall.EBADF)
+	}
+	sym, err := load.OpenSymbol(libc, "close")
+	if err != nil {
+		t.Fatalf("failed to find close: %s", err)
+	}
+	if _, _, errno := purego.SyscallN(sym, ^uintptr(0)); syscall.Errno(errno) != syscall.EBADF {
+		t.Errorf("SyscallN(close, -1) failed. got %v but wanted %v", syscall.Errno(errno), syscall.EBADF)
+	}
+
+	// functions without an error result don't touch errno
+	errnoLocationSymbol := "__errno"
+	switch runtime.GOOS {
+	case "darwin", "freebsd":
+		errnoLocationSymbol = "__error"
+	case "linux":
+		errnoLocationSymbol = "__errno_location"
+	}
+	var errnoLocation func() *int32
+	purego.RegisterLibFunc(&errnoLocation, libc, errnoLocationSymbol)
+	var getpidNoErr func() int32
+	purego.RegisterLibFunc(&getpidNoErr, libc, "getpid")
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	*errnoLocation() = int32(syscall.EINTR)
+	getpidNoErr()
+	if errno := syscall.Errno(*errnoLocation()); errno != syscall.EINTR {
+		t.Errorf("getpid changed errno. got %v but wanted %v", errno, syscall.EINTR)
+	}
+}
+
+func TestRegisterFunc_TwoResults(t *testing.T) {
+	if (runtime.GOOS != "darwin" && runtime.GOOS != "linux") || (runtime.GOARCH != "arm64" && runtime.GOARCH != "amd64") {
+		t.Skip("two results are only supported on Darwin and Linux ARM64/AMD64")
+	}
+	library, err := getSystemLibrary()
+	if err != nil {
+		t.Fatalf("couldn't get system library: %s", err)
+	}
+	libc, err := load.OpenLibrary(library)
+	if err != nil {
+		t.Fatalf("failed to dlopen: %s", err)
+	}
+	var ldiv func(x, y int64) (quot, rem int64)
+	purego.RegisterLibFunc(&ldiv, libc, "ldiv")
+	if q, r := ldiv(-17, 5); q != -3 || r != -2 {
+		t.Errorf("ldiv(-17, 5) failed. got (%d, %d) but wanted (-3, -2)", q, r)
+	}
+	var div func(x, y int32) (quot, rem int32)
+	purego.RegisterLibFunc(&div, libc, "div")
+	if q, r := div(17, 5); q != 3 || r != 2 {
+		t.Errorf("div(17, 5) failed. got (%d, %d) but wanted (3, 2)", q, r)
+	}
+
+	libFileName := filepath.Join(t.TempDir(), "structreturntest.so")
+	if err := buildSharedLib("CC", libFileName, filepath.Join("testdata", "structtest", "structreturn_test.c")); err != nil {
+		t.Fatal(err)
+	}
+	lib, err := load.OpenLibrary(libFileName)
+	if err != nil {
+		t.Fatalf("Failed to open library %q: %v", libFileName, err)
+	}
+	t.Cleanup(func() {
+		if err := load.CloseLibrary(lib); err != nil {
+			t.Errorf("Failed to close library: %v", err)
+		}
+	})
+	var intDouble func(a int32, b float64) (int32, float64)
+	purego.RegisterLibFunc(&intDouble, lib, "ReturnIntDouble")
+	if a, b := intDouble(-4, 2.5); a != -4 || b != 2.5 {
+		t.Errorf("ReturnIntDouble failed. got (%d, %f) but wanted (-4, 2.5)", a, b)
+	}
+	var twoFloats func(a, b float32) (float32, float32)
+	purego.RegisterLibFunc(&twoFloats, lib, "ReturnTwoFloats")
+	if a, b := twoFloats(1.5, 3); a != -1.5 || b != 4.5 {
+		t.Errorf("ReturnTwoFloats failed. got (%f, %f) but wanted (-1.5, 4.5)", a, b)
+	}
+	x := int64(7)
+	var ptrs func(a *int64, b unsafe.Pointer) (*int64, unsafe.Pointer)
+	purego.RegisterLibFunc(&ptrs, lib, "ReturnPtr1")
+	if a, b := ptrs(&x, unsafe.Pointer(&x)); a != &x || b != unsafe.Pointer(&x) {
+		t.Errorf("ReturnPtr1 failed. got (%p, %p) but wanted (%p, %p)", a, b, &x, &x)
+	}
+	var withErrno func(a, b int64) (int64, int64, error)
+	purego.RegisterLibFunc(&withErrno, lib, "ReturnTwoLongs")
+	if a, b, err := withErrno(1, 2); a != 1 || b != 2 || err != nil {
+		t.Errorf("ReturnTwoLongs failed. got (%d, %d, %v) but wanted (1, 2, <nil>)", a, b, err)
+	}
+
+	var tooMany func() (int64, int64, int64)
+	if err := purego.RegisterFuncE(&tooMany, 1); err == nil {
+		t.Errorf("RegisterFuncE of three results succeeded")
+	}
+}
+
+func TestRegisterFunc_Strings(t *testing.T) {
+	if runtime.GOOS == "windows" && runtime.GOARCH == "386" {
+		t.Skip("need a 32bit gcc to run this test") // TODO: find 32bit gcc for test
+	}
+	libFileName := filepath.Join(t.TempDir(), "abitest.so")
+	if err := buildSharedLib("CC", libFileName, filepath.Join("testdata", "abitest", "abi_test.c")); err != nil {
+		t.Fatal(err)
+	}
+	lib, err := load.OpenLibrary(libFileName)
+	if err != nil {
+		t.Fatalf("Dlopen(%q) failed: %v", libFileName, err)
+	}
+	defer func() {
+		if err := load.CloseLibrary(lib); err != nil {
+			t.Fatalf("failed to close library: %s", err)
+		}
+	}()
+
+	var joinStrings func(buf []byte, strs []string) int32
+	purego.RegisterLibFunc(&joinStrings, lib, "join_strings")
+	buf := make([]byte, 64)
+	if n := joinStrings(buf, []string{"a", "bc", "", "def\x00"}); n != 4 {
+		t.Errorf("join_strings returned %d but wanted %d", n, 4)
+	}
+	if got, want := string(buf[:bytes.IndexByte(buf, 0)]), "a,bc,,def"; got != want {
+		t.Errorf("join_strings wrote %q but wanted %q", got, want)
+	}
+	if n := joinStrings(buf, []string{}); n != 0 {
+		t.Errorf("join_strings of no strings returned %d", n)
+	}
+
+	var replaceString func(s *string)
+	purego.RegisterLibFunc(&replaceString, lib, "replace_string")
+	for _, tt := range []struct{ in, want string }{{"old", "new"}, {"other", "other"}} {
+		s := tt.in
+		replaceString(&s)
+		if s != tt.want {
+			t.Errorf("replace_string(%q) set %q but wanted %q", tt.in, s, tt.want)
+		}
+	}
+
+	library, err := getSystemLibrary()
+	if err != nil {
+		t.Fatalf("couldn't get system library: %s", err)
+	}
+	libc, err := load.OpenLibrary(library)
+	if err != nil {
+		t.Fatalf("failed to dlopen: %s", err)
+	}
+	var strtol func(s string, end *string, base int32) int64
+	purego.RegisterLibFunc(&strtol, libc, "strtol")
+	var end string
+	if n := strtol("42 rest", &end, 10); n != 42 || end != " rest" {
+		t.Errorf("strtol failed. got (%d, %q) but wanted (42, %q)", n, end, " rest")
+	}
+}
+
 func TestRegisterLibFunc_Bool(t *testing.T) {
 	if runtime.GOARCH != "arm64" && runtime.GOARCH != "amd64" && runtime.GOARCH != "loong64" {
 		t.Skip("Platform doesn't support callbacks")
@@ -280,6 +509,28 @@ func TestABI_ArgumentPassing(t *testing.T) {
 			},
 			want: "1:2:3:4:5:6:7:8:9:10:11:12:13:14:15:16:17:18:19:20",
 		},
+		{
+			name: "25_int64",
+			fn:   new(func(*byte, uintptr, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64)),
+			cFn:  "stack_25_int64_exceeds",
+			call: func(f any) string {
+				buf := make([]byte, 512)
+				(*f.(*func(*byte, uintptr, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64)))(&buf[0], 512, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25)
+				return string(buf[:bytes.IndexByte(buf, 0)])
+			},
+			want: "1:2:3:4:5:6:7:8:9:10:11:12:13:14:15:16:17:18:19:20:21:22:23:24:25",
+		},
+		{
+			name: "10_int64_10_double",
+			fn:   new(func(*byte, uintptr, int64, float64, int64, float64, int64, float64, int64, float64, int64, float64, int64, float64, int64, float64, int64, float64, int64, float64, int64, float64)),
+			cFn:  "stack_10_int64_10_double",
+			call: func(f any) string {
+				buf := make([]byte, 512)
+				(*f.(*func(*byte, uintptr, int64, float64, int64, float64, int64, float64, int64, float64, int64, float64, int64, float64, int64, float64, int64, float64, int64, float64, int64, float64)))(&buf[0], 512, 1, 1.5, 2, 2.5, 3, 3.5, 4, 4.5, 5, 5.5, 6, 6.5, 7, 7.5, 8, 8.5, 9, 9.5, 10, 10.5)
+				return string(buf[:bytes.IndexByte(buf, 0)])
+			},
+			want: "1:1.5:2:2.5:3:3.5:4:4.5:5:5.5:6:6.5:7:7.5:8:8.5:9:9.5:10:10.5",
+		},
 		{
 			name: "8int_hfa2_stack",
 			fn:   new(func(*byte, uintptr, int32, int32, int32, int32, int32, int32, int32, int32, struct{ x, y float32 })),
@@ -368,20 +619,22 @@ func TestABI_ArgumentPassing(t *testing.T) {
 		},
 	}
 
+	stackArgsUnlimited := (runtime.GOARCH == "amd64" && runtime.GOOS != "windows") || runtime.GOARCH == "arm64" || runtime.GOARCH == "loong64"
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if tt.name == "20_int32" && (runtime.GOOS != "darwin" || runtime.GOARCH != "arm64") {
-				t.Skip("20 int32 arguments only supported on Darwin ARM64 with smart stack checking")
+			if (tt.name == "20_int32" || tt.name == "25_int64" || tt.name == "10_int64_10_double") && !stackArgsUnlimited {
+				t.Skip("more than 15 arguments are not supported on this platform")
 			}
 			if tt.name == "10_float32" && (runtime.GOARCH == "386" || runtime.GOARCH == "arm" || runtime.GOARCH == "loong64") {
 				t.Skip("float32 stack arguments not yet supported on this platform")
 			}
-			// Struct tests require Darwin ARM64 or AMD64
-			if strings.HasPrefix(tt.name, "8int_") && (runtime.GOOS != "darwin" || (runtime.GOARCH != "arm64" && runtime.GOARCH != "amd64")) {
-				t.Skip("struct argument tests only supported on Darwin ARM64/AMD64")
+			// Struct tests require Darwin or Linux on ARM64 or AMD64
+			structsSupported := (runtime.GOOS == "darwin" || runtime.GOOS == "linux") && (runtime.GOARCH == "arm64" || runtime.GOARCH == "amd64")
+			if strings.HasPrefix(tt.name, "8int_") && !structsSupported {
+				t.Skip("struct argument tests only supported on Darwin and Linux ARM64/AMD64")
 			}
-			if strings.HasPrefix(tt.name, "8float_") && (runtime.GOOS != "darwin" || (runtime.GOARCH != "arm64" && runtime.GOARCH != "amd64")) {
-				t.Skip("struct argument tests only supported on Darwin ARM64/AMD64")
+			if strings.HasPrefix(tt.name, "8float_") && !structsSupported {
+				t.Skip("struct argument tests only supported on Darwin and Linux ARM64/AMD64")
 			}
 
 			purego.RegisterLibFunc(tt.fn, lib, tt.cFn)
@@ -393,9 +646,9 @@ func TestABI_ArgumentPassing(t *testing.T) {
 	}
 }
 
-func TestABI_TooManyArguments(t *testing.T) {
-	if runtime.GOOS != "darwin" || runtime.GOARCH != "arm64" {
-		t.Skip("This test is specific to Darwin ARM64")
+func TestSyscallN_ManyArguments(t *testing.T) {
+	if runtime.GOOS == "windows" || (runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" && runtime.GOARCH != "loong64") {
+		t.Skip("SyscallN is limited to 15 arguments on this platform")
 	}
 
 	libFileName := filepath.Join(t.TempDir(), "abitest.so")
@@ -411,20 +664,32 @@ func TestABI_TooManyArguments(t *testing.T) {
 			t.Errorf("Failed to close library: %v", err)
 		}
 	})
+	sym, err := load.OpenSymbol(lib, "stack_25_int64_exceeds")
+	if err != nil {
+		t.Fatalf("Failed to find stack_25_int64_exceeds: %v", err)
+	}
 
-	// Test that 25 int64 arguments (17 slots needed) exceeds the limit
-	t.Run("25_int64_exceeds_limit", func(t *testing.T) {
-		defer func() {
-			if r := recover(); r != nil {
-				t.Logf("Got expected panic: %v", r)
-			} else {
-				t.Errorf("Expected panic but didn't get one")
-			}
-		}()
+	buf := make([]byte, 512)
+	args := []uintptr{uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf))}
+	for i := 1; i <= 25; i++ {
+		args = append(args, uintptr(i))
+	}
+	purego.SyscallN(sym, args...)
+	const want = "1:2:3:4:5:6:7:8:9:10:11:12:13:14:15:16:17:18:19:20:21:22:23:24:25"
+	if got := string(buf[:bytes.IndexByte(buf, 0)]); got != want {
+		t.Errorf("stack_25_int64_exceeds\n  got:  %q\n  want: %q", got, want)
+	}
 
-		var fn func(*byte, uintptr, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64, int64)
-		purego.RegisterLibFunc(&fn, lib, "stack_25_int64_exceeds")
-	})
+	// the stack arguments of the last call must not be passed again by the fast paths
+	var bench3Int func(a, b, c int64) int64
+	purego.RegisterLibFunc(&bench3Int, lib, "bench_3int")
+	for i := 0; i < 10; i++ {
+		purego.SyscallN(sym, args...)
+		runtime.GC()
+		if got := bench3Int(1, 2, 3); got != 6 {
+			t.Fatalf("bench_3int(1, 2, 3) after a call with stack arguments returned %d wanted 6", got)
+		}
+	}
 }
 
 func buildSharedLib(compilerEnv, libFile string, sources ...string) error {
@@ -20,7 +20,10 @@ typedef struct syscall15Args {
 	uintptr_t fn;
 	uintptr_t a1, a2, a3, a4, a5, a6, a7, a8, a9, a10, a11, a12, a13, a14, a15;
 	uintptr_t f1, f2, f3, f4, f5, f6, f7, f8;
+	uintptr_t arm64_r8;
 	uintptr_t err;
+	uintptr_t stackArgs, numStackArgs;
+	uintptr_t amd64_x87;
 } syscall15Args;
 
 void syscall15(struct syscall15Args *args) {
@@ -29,6 +32,7 @@ void syscall15(struct syscall15Args *args) {
 		uintptr_t a7, uintptr_t a8, uintptr_t a9, uintptr_t a10, uintptr_t a11, uintptr_t a12,
 		uintptr_t a13, uintptr_t a14, uintptr_t a15);
 	*(void**)(&func_name) = (void*)(args->fn);
+	errno = 0;
 	uintptr_t r1 =  func_name(args->a1,args->a2,args->a3,args->a4,args->a5,args->a6,args->a7,args->a8,args->a9,
 		args->a10,args->a11,args->a12,args->a13,args->a14,args->a15);
 	args->a1 = r1;
@@ -48,7 +52,7 @@ func Syscall15X(fn, a1, a2, a3, a4, a5, a6, a7, a8, a9, a10, a11, a12, a13, a14,
 		C.uintptr_t(fn), C.uintptr_t(a1), C.uintptr_t(a2), C.uintptr_t(a3),
 		C.uintptr_t(a4), C.uintptr_t(a5), C.uintptr_t(a6),
 		C.uintptr_t(a7), C.uintptr_t(a8), C.uintptr_t(a9), C.uintptr_t(a10), C.uintptr_t(a11), C.uintptr_t(a12),
-		C.uintptr_t(a13), C.uintptr_t(a14), C.uintptr_t(a15), 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		C.uintptr_t(a13), C.uintptr_t(a14), C.uintptr_t(a15), 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
 	}
 	C.syscall15(&args)
 	return uintptr(args.a1), 0, uintptr(args.err)
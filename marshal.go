@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build darwin || freebsd || linux || netbsd || windows
+
+package purego
+
+import (
+	"math"
+	"reflect"
+)
+
+// CMarshaler is implemented by Go types that lower themselves to the integer and floating-point
+// values a C function receives. RegisterFunc calls MarshalC on arguments of such types instead of
+// converting them by their kind. The result of a callback created by NewCallback may also be a CMarshaler.
+//
+// The integer values are passed in order like integer arguments and the floating-point values
+// like float64 arguments after them. A C struct that is passed in registers is lowered to the
+// values of its registers. For example, a struct timespec on Linux amd64 or arm64 is two integers:
+//
+//	type Timeout time.Duration
+//
+//	func (Timeout) CSize() (ints, floats int) {
+//		return 2, 0
+//	}
+//
+//	func (t Timeout) MarshalC(ints []uintptr, floats []float64) ([]uintptr, []float64) {
+//		d := time.Duration(t)
+//		return append(ints, uintptr(d/time.Second), uintptr(d%time.Second)), floats
+//	}
+type CMarshaler interface {
+	// CSize returns the number of integer and floating-point values MarshalC appends.
+	// It is called on the zero value when a function is registered so it must not depend on the receiver.
+	CSize() (ints, floats int)
+
+	// MarshalC appends the values of the receiver to ints and floats and returns them.
+	// It must append as many values as CSize returns. A float64 is passed as a C double.
+	// For a C float put the bits of the float32 into the lower 32 bits of the float64.
+	MarshalC(ints []uintptr, floats []float64) ([]uintptr, []float64)
+}
+
+// CUnmarshaler is implemented by pointers to Go types that lift themselves from the integer and
+// floating-point values a C function returns. RegisterFunc calls UnmarshalC on a new value when such
+// a type is the result of a function. A callback created by NewCallback calls it for arguments of such
+// types if the type is also a CMarshaler whose CSize tells how many values the argument takes.
+//
+//	func (t *Timeout) UnmarshalC(ints []uintptr, floats []float64) {
+//		*t = Timeout(time.Duration(ints[0])*time.Second + time.Duration(ints[1]))
+//	}
+type CUnmarshaler interface {
+	// UnmarshalC sets the receiver from ints and floats. For a result these are the two integer
+	// and the first two floating-point result registers. For a callback argument these are as many
+	// values as MarshalC appends.
+	UnmarshalC(ints []uintptr, floats []float64)
+}
+
+var (
+	cMarshalerType   = reflect.TypeOf((*CMarshaler)(nil)).Elem()
+	cUnmarshalerType = reflect.TypeOf((*CUnmarshaler)(nil)).Elem()
+)
+
+// isCMarshaler reports whether values of type t are CMarshalers.
+func isCMarshaler(t reflect.Type) bool {
+	return t.Implements(cMarshalerType)
+}
+
+// isCUnmarshaler reports whether pointers to t are CUnmarshalers.
+func isCUnmarshaler(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(cUnmarshalerType)
+}
+
+// marshalC returns the integer and floating-point values of v which is a CMarshaler.
+// It panics if MarshalC doesn't append as many values as CSize returns.
+func marshalC(v reflect.Value) (ints []uintptr, floats []float64) {
+	m := v.Interface().(CMarshaler)
+	numInts, numFloats := m.CSize()
+	ints, floats = m.MarshalC(make([]uintptr, 0, numInts), make([]float64, 0, numFloats))
+	if len(ints) != numInts || len(floats) != numFloats {
+		panic("purego: MarshalC of " + v.Type().String() + " didn't append as many values as CSize returns")
+	}
+	return ints, floats
+}
+
+// numMarshaledValues returns the number of integer and floating-point values of the CMarshaler type t.
+func numMarshaledValues(t reflect.Type) (numInts, numFloats int) {
+	return reflect.Zero(t).Interface().(CMarshaler).CSize()
+}
+
+// unmarshalC returns a new value of type t set from ints and floats.
+func unmarshalC(t reflect.Type, ints []uintptr, floats []float64) reflect.Value {
+	v := reflect.New(t)
+	v.Interface().(CUnmarshaler).UnmarshalC(ints, floats)
+	return v.Elem()
+}
+
+// expandCMarshalers returns args with every CMarshaler replaced by its values as uintptrs and float64s.
+// It is only needed where the stack arguments are packed by the type of each argument.
+func expandCMarshalers(args []reflect.Value) []reflect.Value {
+	expanded := make([]reflect.Value, 0, len(args))
+	for _, v := range args {
+		if !isCMarshaler(v.Type()) {
+			expanded = append(expanded, v)
+			continue
+		}
+		ints, floats := marshalC(v)
+		for _, x := range ints {
+			expanded = append(expanded, reflect.ValueOf(x))
+		}
+		for _, x := range floats {
+			expanded = append(expanded, reflect.ValueOf(x))
+		}
+	}
+	return expanded
+}
+
+// addCMarshaler places the values of v which is a CMarshaler.
+func addCMarshaler(v reflect.Value, addInt, addFloat func(uintptr)) {
+	ints, floats := marshalC(v)
+	for _, x := range ints {
+		addInt(x)
+	}
+	for _, x := range floats {
+		addFloat(uintptr(math.Float64bits(x)))
+	}
+}
@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+package purego_test
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/ebitengine/purego"
+	"github.com/ebitengine/purego/internal/load"
+)
+
+// timeout is passed to C as a struct of seconds and nanoseconds.
+type timeout time.Duration
+
+func (timeout) CSize() (ints, floats int) {
+	return 2, 0
+}
+
+func (t timeout) MarshalC(ints []uintptr, floats []float64) ([]uintptr, []float64) {
+	d := time.Duration(t)
+	return append(ints, uintptr(d/time.Second), uintptr(d%time.Second)), floats
+}
+
+func (t *timeout) UnmarshalC(ints []uintptr, floats []float64) {
+	*t = timeout(time.Duration(ints[0])*time.Second + time.Duration(ints[1]))
+}
+
+// sizedTimeout is a timeout that counts the calls of CSize.
+type sizedTimeout struct {
+	timeout
+}
+
+var sizedTimeoutCSizeCalls int
+
+func (sizedTimeout) CSize() (ints, floats int) {
+	sizedTimeoutCSizeCalls++
+	return 2, 0
+}
+
+// millis is a number of milliseconds that must be positive.
+type millis int64
+
+func (millis) CSize() (ints, floats int) {
+	return 1, 0
+}
+
+func (m millis) MarshalC(ints []uintptr, floats []float64) ([]uintptr, []float64) {
+	if m <= 0 {
+		panic("milliseconds must be positive")
+	}
+	return append(ints, uintptr(m)), floats
+}
+
+func (m *millis) UnmarshalC(ints []uintptr, floats []float64) {
+	*m = millis(ints[0])
+}
+
+func TestCMarshaler(t *testing.T) {
+	if runtime.GOOS == "windows" || (runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64") {
+		t.Skip("a struct of two int64 is only passed in registers on Darwin and Linux ARM64/AMD64")
+	}
+	libFileName := filepath.Join(t.TempDir(), "abitest.so")
+	if err := buildSharedLib("CC", libFileName, filepath.Join("testdata", "abitest", "abi_test.c")); err != nil {
+		t.Fatal(err)
+	}
+	lib, err := load.OpenLibrary(libFileName)
+	if err != nil {
+		t.Fatalf("Dlopen(%q) failed: %v", libFileName, err)
+	}
+	defer func() {
+		if err := load.CloseLibrary(lib); err != nil {
+			t.Fatalf("failed to close library: %s", err)
+		}
+	}()
+
+	var durationMs func(timeout) millis
+	purego.RegisterLibFunc(&durationMs, lib, "duration_ms")
+	if got := durationMs(timeout(2*time.Second + 500*time.Millisecond)); got != 2500 {
+		t.Errorf("duration_ms returned %d but wanted %d", got, 2500)
+	}
+
+	var msDuration func(millis) timeout
+	purego.RegisterLibFunc(&msDuration, lib, "ms_duration")
+	if got, want := msDuration(1250), timeout(1250*time.Millisecond); got != want {
+		t.Errorf("ms_duration returned %v but wanted %v", time.Duration(got), time.Duration(want))
+	}
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("ms_duration of a negative value didn't panic")
+			}
+		}()
+		msDuration(-1)
+	}()
+
+	var callWithDuration func(cb uintptr, ms millis, scale float64) int64
+	purego.RegisterLibFunc(&callWithDuration, lib, "call_with_duration")
+	cb := purego.NewCallback(func(d timeout, scale float64) millis {
+		return millis(float64(time.Duration(d).Milliseconds()) * scale)
+	})
+	if got := callWithDuration(cb, 3000, 0.5); got != 1500 {
+		t.Errorf("call_with_duration returned %d but wanted %d", got, 1500)
+	}
+
+	// the CSize of callback arguments is only asked for when the callback is created
+	cb = purego.NewCallback(func(d sizedTimeout, scale float64) millis {
+		return millis(float64(time.Duration(d.timeout).Milliseconds()) * scale)
+	})
+	calls := sizedTimeoutCSizeCalls
+	for i := 0; i < 2; i++ {
+		if got := callWithDuration(cb, 3000, 2); got != 6000 {
+			t.Errorf("call_with_duration returned %d but wanted %d", got, 6000)
+		}
+	}
+	if sizedTimeoutCSizeCalls != calls {
+		t.Errorf("calling the callback called CSize %d times", sizedTimeoutCSizeCalls-calls)
+	}
+}
@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build darwin || freebsd || linux || netbsd || windows
+
+package purego
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// Out is an out-parameter of a C function that takes a pointer to a T like int *count or struct stat *buf.
+// Declare the parameter as Out[T] and pass the value returned by NewOut:
+//
+//	// int stat(const char *path, struct stat *buf);
+//	var stat func(path string, buf purego.Out[Stat]) int32
+//	var st Stat
+//	stat("/tmp", purego.NewOut(&st))
+//
+// Purego passes a pointer to a temporary copy of the T with its C memory layout instead of the T itself.
+// The copy is pinned and only valid for the duration of the call. It is then copied back into the T.
+// So the C function never sees Go memory of the caller and can't keep a reference to it. A nil pointer
+// is passed as NULL. T must not contain Go pointers, strings or funcs which RegisterFunc rejects.
+type Out[T any] struct {
+	p *T
+}
+
+// NewOut returns an out-parameter that copies the value of the C function into *p.
+func NewOut[T any](p *T) Out[T] {
+	return Out[T]{p: p}
+}
+
+func (o Out[T]) target() reflect.Value {
+	return reflect.ValueOf(o.p)
+}
+
+// outParam is implemented by every Out type.
+type outParam interface {
+	target() reflect.Value
+}
+
+var outParamType = reflect.TypeOf((*outParam)(nil)).Elem()
+
+// isOut reports whether t is an Out type.
+func isOut(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.Implements(outParamType)
+}
+
+// checkOut panics if the T of the Out type t contains Go pointers which the pinned copy would pass to C.
+func checkOut(t reflect.Type) {
+	if elem := t.Field(0).Type.Elem(); hasPointers(elem) {
+		panic("purego: the type of an Out must not contain Go pointers: " + elem.String())
+	}
+}
+
+// outValue is the temporary C memory of an Out argument.
+type outValue struct {
+	target reflect.Value // the *T
+	c      reflect.Value // the addressable copy of the T with its C layout
+	layout *structLayout // the C layout of the T or nil if it is the same as the Go layout
+	pinner outPinner
+}
+
+// outArg returns a pointer to the temporary copy of the T of v which is an Out[T].
+// The copy is kept alive by the returned keepAlive, copied back by copyOutParams and unpinned by unpinOutParams.
+func outArg(v reflect.Value, keepAlive []any) (unsafe.Pointer, []any) {
+	out := &outValue{target: v.Interface().(outParam).target()}
+	if out.target.IsNil() {
+		return nil, keepAlive
+	}
+	elem := out.target.Elem()
+	if elem.Kind() == reflect.Struct {
+		out.layout = structLayoutOf(elem.Type())
+	}
+	if out.layout != nil {
+		out.c = out.layout.toC(elem)
+	} else {
+		out.c = reflect.New(elem.Type()).Elem()
+		out.c.Set(elem)
+	}
+	out.pinner.Pin(out.c.Addr().Interface())
+	return out.c.Addr().UnsafePointer(), append(keepAlive, out)
+}
+
+func (out *outValue) copyBack() {
+	if out.layout != nil {
+		out.target.Elem().Set(out.layout.fromC(out.c, out.target.Type().Elem()))
+		return
+	}
+	out.target.Elem().Set(out.c)
+}
+
+// unpinOutParams unpins the copies of the out-parameters in keepAlive.
+// It is deferred so that they are unpinned even if placing a later argument panics.
+func unpinOutParams(keepAlive []any) {
+	for _, x := range keepAlive {
+		if out, ok := x.(*outValue); ok {
+			out.pinner.Unpin()
+		}
+	}
+}
@@ -0,0 +1,13 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build (darwin || freebsd || linux || netbsd || windows) && !go1.21
+
+package purego
+
+// outPinner does nothing because Go memory doesn't move before runtime.Pinner exists.
+type outPinner struct{}
+
+func (*outPinner) Pin(pointer any) {}
+
+func (*outPinner) Unpin() {}
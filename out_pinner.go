@@ -0,0 +1,11 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build (darwin || freebsd || linux || netbsd || windows) && go1.21
+
+package purego
+
+import "runtime"
+
+// outPinner pins the temporary copy of an Out for the duration of the call.
+type outPinner = runtime.Pinner
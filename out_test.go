@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+package purego_test
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/ebitengine/purego"
+	"github.com/ebitengine/purego/internal/load"
+	"github.com/ebitengine/purego/internal/strings"
+)
+
+func TestOut(t *testing.T) {
+	if runtime.GOOS == "windows" && runtime.GOARCH == "386" {
+		t.Skip("need a 32bit gcc to run this test") // TODO: find 32bit gcc for test
+	}
+	libFileName := filepath.Join(t.TempDir(), "abitest.so")
+	if err := buildSharedLib("CC", libFileName, filepath.Join("testdata", "abitest", "abi_test.c")); err != nil {
+		t.Fatal(err)
+	}
+	lib, err := load.OpenLibrary(libFileName)
+	if err != nil {
+		t.Fatalf("Dlopen(%q) failed: %v", libFileName, err)
+	}
+	defer func() {
+		if err := load.CloseLibrary(lib); err != nil {
+			t.Fatalf("failed to close library: %s", err)
+		}
+	}()
+
+	var addCount func(count purego.Out[int32], n int32)
+	purego.RegisterLibFunc(&addCount, lib, "add_count")
+	count := int32(3)
+	addCount(purego.NewOut(&count), 4)
+	if count != 7 {
+		t.Errorf("add_count set %d but wanted %d", count, 7)
+	}
+
+	// the C struct is laid out by the c tag and name is the address of a C string
+	type dims struct {
+		kind  int8
+		width int32 `c:"offset=4"`
+		name  uintptr
+	}
+	var getDims func(d purego.Out[dims]) int32
+	purego.RegisterLibFunc(&getDims, lib, "get_dims")
+	d := dims{kind: 1}
+	if r := getDims(purego.NewOut(&d)); r != 0 {
+		t.Errorf("get_dims returned %d but wanted 0", r)
+	}
+	if d.kind != 2 || d.width != 640 || strings.GoString(d.name) != "screen" {
+		t.Errorf("get_dims set %+v with name %q but wanted {kind:2 width:640} with name %q", d, strings.GoString(d.name), "screen")
+	}
+	if r := getDims(purego.NewOut[dims](nil)); r != -1 {
+		t.Errorf("get_dims of NULL returned %d but wanted -1", r)
+	}
+
+	// the copy of the T is passed to C so it must not contain Go pointers
+	type named struct {
+		kind  int8
+		width int32 `c:"offset=4"`
+		name  string
+	}
+	var getNamed func(d purego.Out[named]) int32
+	if err := purego.Validate(&getNamed); err == nil {
+		t.Errorf("Validate accepted an Out with a string field")
+	}
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("registering an Out with a string field didn't panic")
+		}
+	}()
+	purego.RegisterLibFunc(&getNamed, lib, "get_dims")
+}
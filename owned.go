@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build darwin || freebsd || linux || netbsd || windows
+
+package purego
+
+import (
+	"reflect"
+	"sync"
+	"unsafe"
+
+	"github.com/ebitengine/purego/internal/strings"
+)
+
+// Freer frees memory that a C function returned to its caller. The zero value of a Freer must be usable.
+type Freer interface {
+	Free(ptr unsafe.Pointer)
+}
+
+// Owned is the result of a C function that returns memory which the caller must free with F.
+// Declare the result as Owned[T, F] to have purego release it:
+//
+//	type libcFree struct{}
+//
+//	func (libcFree) Free(ptr unsafe.Pointer) { free(ptr) }
+//
+//	// char *strdup(const char *s);
+//	var strdup func(s string) purego.Owned[string, libcFree]
+//	s := strdup("hello").Value()
+//
+// If T is a string the characters are copied into Go memory and the C string is freed immediately.
+// If T is a pointer, an unsafe.Pointer or a uintptr the handle is freed after the Owned and every copy
+// of it became unreachable or when Free is called, whichever happens first. The handle must not be used
+// after that so keep the Owned alive while the value is in use, for example with runtime.KeepAlive.
+// NULL is never freed. RegisterFunc panics for any other T.
+//
+// The handle is freed on a goroutine of the runtime if it is collected. F must not block for long.
+type Owned[T any, F Freer] struct {
+	value T
+	h     *ownedPtr
+}
+
+// Value returns the value of o.
+func (o Owned[T, F]) Value() T {
+	return o.value
+}
+
+// Free frees the handle of o now instead of when o becomes unreachable.
+// It does nothing if o is a string, NULL or was already freed.
+func (o Owned[T, F]) Free() {
+	if o.h != nil {
+		o.h.release()
+	}
+}
+
+// UnmarshalC sets o from the result of a C function which is the pointer in ints[0].
+// It lets RegisterFunc return an Owned like any other CUnmarshaler.
+func (o *Owned[T, F]) UnmarshalC(ints []uintptr, floats []float64) {
+	v := reflect.ValueOf(&o.value).Elem()
+	var f F
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(strings.GoString(ints[0]))
+		if ints[0] != 0 {
+			f.Free(*(*unsafe.Pointer)(unsafe.Pointer(&ints[0])))
+		}
+		return
+	case reflect.Ptr, reflect.UnsafePointer, reflect.Uintptr:
+		*(*uintptr)(unsafe.Pointer(v.UnsafeAddr())) = ints[0]
+	default:
+		panic("purego: unsupported type of an Owned value: " + v.Type().String())
+	}
+	if ints[0] != 0 {
+		o.h = newOwnedPtr(*(*unsafe.Pointer)(unsafe.Pointer(&ints[0])), f.Free)
+	}
+}
+
+// checkResult panics if T isn't supported so that RegisterFunc rejects the Owned before it is called.
+func (o *Owned[T, F]) checkResult() {
+	switch t := reflect.TypeOf(&o.value).Elem(); t.Kind() {
+	case reflect.String, reflect.Ptr, reflect.UnsafePointer, reflect.Uintptr:
+	default:
+		panic("purego: unsupported type of an Owned value: " + t.String())
+	}
+}
+
+// resultChecker is a CUnmarshaler that only supports some types and checks them when it is registered.
+type resultChecker interface {
+	checkResult()
+}
+
+// ownedPtr frees ptr when it is released or collected.
+// The cleanup field and its functions are defined per Go version.
+type ownedPtr struct {
+	once    sync.Once
+	ptr     unsafe.Pointer
+	free    func(unsafe.Pointer)
+	cleanup ownedCleanup
+}
+
+func newOwnedPtr(ptr unsafe.Pointer, free func(unsafe.Pointer)) *ownedPtr {
+	h := &ownedPtr{ptr: ptr, free: free}
+	h.addCleanup()
+	return h
+}
+
+func (h *ownedPtr) release() {
+	h.once.Do(func() {
+		h.stopCleanup()
+		h.free(h.ptr)
+	})
+}
@@ -0,0 +1,19 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build (darwin || freebsd || linux || netbsd || windows) && go1.24
+
+package purego
+
+import "runtime"
+
+type ownedCleanup = runtime.Cleanup
+
+func (h *ownedPtr) addCleanup() {
+	// the cleanup must not reference h or it would never become unreachable
+	h.cleanup = runtime.AddCleanup(h, h.free, h.ptr)
+}
+
+func (h *ownedPtr) stopCleanup() {
+	h.cleanup.Stop()
+}
@@ -0,0 +1,19 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build (darwin || freebsd || linux || netbsd || windows) && !go1.24
+
+package purego
+
+import "runtime"
+
+// ownedCleanup is empty because a finalizer is stored by the runtime.
+type ownedCleanup struct{}
+
+func (h *ownedPtr) addCleanup() {
+	runtime.SetFinalizer(h, func(h *ownedPtr) { h.free(h.ptr) })
+}
+
+func (h *ownedPtr) stopCleanup() {
+	runtime.SetFinalizer(h, nil)
+}
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+package purego_test
+
+import (
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/ebitengine/purego"
+	"github.com/ebitengine/purego/internal/load"
+)
+
+var (
+	libcFreeFunc func(unsafe.Pointer)
+	numFreed     int64
+)
+
+// countingFree calls the free of libc and counts how often it was called.
+type countingFree struct{}
+
+func (countingFree) Free(ptr unsafe.Pointer) {
+	libcFreeFunc(ptr)
+	atomic.AddInt64(&numFreed, 1)
+}
+
+func TestOwned(t *testing.T) {
+	library, err := getSystemLibrary()
+	if err != nil {
+		t.Fatalf("couldn't get system library: %s", err)
+	}
+	libc, err := load.OpenLibrary(library)
+	if err != nil {
+		t.Fatalf("failed to dlopen: %s", err)
+	}
+	purego.RegisterLibFunc(&libcFreeFunc, libc, "free")
+
+	strdupName := "strdup"
+	if runtime.GOOS == "windows" {
+		strdupName = "_strdup"
+	}
+	var strdup func(string) purego.Owned[string, countingFree]
+	purego.RegisterLibFunc(&strdup, libc, strdupName)
+	before := atomic.LoadInt64(&numFreed)
+	if got := strdup("owned").Value(); got != "owned" {
+		t.Errorf("strdup returned %q but wanted %q", got, "owned")
+	}
+	if got := atomic.LoadInt64(&numFreed) - before; got != 1 {
+		t.Errorf("a string was freed %d times but wanted 1", got)
+	}
+
+	var malloc func(uintptr) purego.Owned[unsafe.Pointer, countingFree]
+	purego.RegisterLibFunc(&malloc, libc, "malloc")
+	before = atomic.LoadInt64(&numFreed)
+	p := malloc(16)
+	if p.Value() == nil {
+		t.Fatal("malloc returned NULL")
+	}
+	p.Free()
+	p.Free()
+	if got := atomic.LoadInt64(&numFreed) - before; got != 1 {
+		t.Errorf("Free freed a pointer %d times but wanted 1", got)
+	}
+
+	before = atomic.LoadInt64(&numFreed)
+	malloc(16)
+	for i := 0; i < 100 && atomic.LoadInt64(&numFreed) == before; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := atomic.LoadInt64(&numFreed) - before; got != 1 {
+		t.Errorf("an unreachable pointer was freed %d times but wanted 1", got)
+	}
+
+	var abs func(int32) purego.Owned[int, countingFree]
+	if err := purego.Validate(&abs); err == nil {
+		t.Errorf("Validate accepted an Owned int")
+	}
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("registering an Owned int didn't panic")
+		}
+	}()
+	purego.RegisterLibFunc(&abs, libc, "abs")
+}
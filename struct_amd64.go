@@ -4,8 +4,8 @@
 package purego
 
 import (
-	"math"
 	"reflect"
+	"strconv"
 	"unsafe"
 )
 
@@ -14,46 +14,28 @@ func getStruct(outType reflect.Type, syscall syscall15Args) (v reflect.Value) {
 	switch {
 	case outSize == 0:
 		return reflect.New(outType).Elem()
-	case outSize <= 8:
-		if isAllFloats(outType) {
-			// 2 float32s or 1 float64s are return in the float register
-			return reflect.NewAt(outType, unsafe.Pointer(&struct{ a uintptr }{syscall.f1})).Elem()
-		}
-		// up to 8 bytes is returned in RAX
-		return reflect.NewAt(outType, unsafe.Pointer(&struct{ a uintptr }{syscall.a1})).Elem()
-	case outSize <= 16:
-		r1, r2 := syscall.a1, syscall.a2
-		if isAllFloats(outType) {
-			r1 = syscall.f1
-			r2 = syscall.f2
-		} else {
-			// check first 8 bytes if it's floats
-			hasFirstFloat := false
-			f1 := outType.Field(0).Type
-			if f1.Kind() == reflect.Float64 || f1.Kind() == reflect.Float32 && outType.Field(1).Type.Kind() == reflect.Float32 {
-				r1 = syscall.f1
-				hasFirstFloat = true
-			}
-
-			// find index of the field that starts the second 8 bytes
-			var i int
-			for i = 0; i < outType.NumField(); i++ {
-				if outType.Field(i).Offset == 8 {
-					break
-				}
-			}
-
-			// check last 8 bytes if they are floats
-			f1 = outType.Field(i).Type
-			if f1.Kind() == reflect.Float64 || f1.Kind() == reflect.Float32 && i+1 == outType.NumField() {
-				r2 = syscall.f1
-			} else if hasFirstFloat {
-				// if the first field was a float then that means the second integer field
-				// comes from the first integer register
-				r2 = syscall.a1
+	case returnsLongDouble(outType):
+		// syscall15X stored ST(0) in f1 and f2
+		return reflect.NewAt(outType, unsafe.Pointer(&struct{ a, b uintptr }{syscall.f1, syscall.f2})).Elem()
+	case outSize <= 16 && !hasUnalignedFields(outType):
+		// Each eightbyte is returned in the next free register of its class.
+		// INTEGER eightbytes use RAX then RDX and SSE eightbytes use XMM0 then XMM1.
+		classes, n := classifyEightbytes(outType)
+		ints := [2]uintptr{syscall.a1, syscall.a2}
+		floats := [2]uintptr{syscall.f1, syscall.f2}
+		var regs struct{ a, b uintptr }
+		words := [2]*uintptr{&regs.a, &regs.b}
+		var numInts, numFloats int
+		for i := 0; i < n; i++ {
+			if classes[i] == _SSE {
+				*words[i] = floats[numFloats]
+				numFloats++
+			} else {
+				*words[i] = ints[numInts]
+				numInts++
 			}
 		}
-		return reflect.NewAt(outType, unsafe.Pointer(&struct{ a, b uintptr }{r1, r2})).Elem()
+		return reflect.NewAt(outType, unsafe.Pointer(&regs)).Elem()
 	default:
 		// create struct from the Go pointer created above
 		// weird pointer dereference to circumvent go vet
@@ -61,18 +43,6 @@ func getStruct(outType reflect.Type, syscall syscall15Args) (v reflect.Value) {
 	}
 }
 
-func isAllFloats(ty reflect.Type) bool {
-	for i := 0; i < ty.NumField(); i++ {
-		f := ty.Field(i)
-		switch f.Type.Kind() {
-		case reflect.Float64, reflect.Float32:
-		default:
-			return false
-		}
-	}
-	return true
-}
-
 // https://refspecs.linuxbase.org/elf/x86_64-abi-0.99.pdf
 // https://gitlab.com/x86-psABIs/x86-64-ABI
 // Class determines where the 8 byte value goes.
@@ -89,23 +59,12 @@ func addStruct(v reflect.Value, numInts, numFloats, numStack *int, addInt, addFl
 	if v.Type().Size() == 0 {
 		return keepAlive
 	}
-
-	// if greater than 64 bytes place on stack
-	if v.Type().Size() > 8*8 {
-		placeStack(v, addStack)
-		return keepAlive
-	}
-	var (
-		savedNumFloats = *numFloats
-		savedNumInts   = *numInts
-		savedNumStack  = *numStack
-	)
-	placeOnStack := postMerger(v.Type()) || !tryPlaceRegister(v, addFloat, addInt)
-	if placeOnStack {
-		// reset any values placed in registers
-		*numFloats = savedNumFloats
-		*numInts = savedNumInts
-		*numStack = savedNumStack
+	// structs with unaligned fields are passed in memory like GCC and Clang do
+	// and a long double is of class X87 which is also passed in memory
+	if t := v.Type(); postMerger(t) || hasUnalignedFields(t) || containsLongDouble(t) || !tryPlaceRegister(v, *numInts, *numFloats, addFloat, addInt) {
+		if cStructAlign(t) > 8 && *numStack%2 != 0 {
+			addStack(0) // align the struct to 16 bytes on the stack
+		}
 		placeStack(v, addStack)
 	}
 	return keepAlive
@@ -123,139 +82,135 @@ func postMerger(t reflect.Type) (passInMemory bool) {
 	return true // Go does not have an SSE/SSEUP type so this is always true
 }
 
-func tryPlaceRegister(v reflect.Value, addFloat func(uintptr), addInt func(uintptr)) (ok bool) {
-	ok = true
-	var val uint64
-	var shift byte // # of bits to shift
-	var flushed bool
-	class := _NO_CLASS
-	flushIfNeeded := func() {
-		if flushed {
-			return
-		}
-		flushed = true
-		if class == _SSE {
-			addFloat(uintptr(val))
-		} else {
-			addInt(uintptr(val))
+// classifyEightbytes returns the class of each eightbyte of a struct that is at most 16 bytes.
+// Every scalar field contributes its class to the eightbyte it lives in and the classes
+// are merged as described in 3.2.3 of the x86-64 psABI.
+func classifyEightbytes(t reflect.Type) (classes [2]int, n int) {
+	n = int((t.Size() + align8ByteMask) / align8ByteSize)
+	mergeClasses(t, 0, &classes)
+	for i := 0; i < n; i++ {
+		if classes[i] == _NO_CLASS {
+			// an eightbyte that only holds padding is passed as an integer
+			classes[i] = _INTEGER
 		}
-		val = 0
-		shift = 0
-		class = _NO_CLASS
 	}
-	var place func(v reflect.Value)
-	place = func(v reflect.Value) {
-		var numFields int
-		if v.Kind() == reflect.Struct {
-			numFields = v.Type().NumField()
-		} else {
-			numFields = v.Type().Len()
-		}
+	return classes, n
+}
 
-		for i := 0; i < numFields; i++ {
-			flushed = false
-			var f reflect.Value
-			if v.Kind() == reflect.Struct {
-				f = v.Field(i)
-			} else {
-				f = v.Index(i)
-			}
-			switch f.Kind() {
-			case reflect.Struct:
-				place(f)
-			case reflect.Bool:
-				if f.Bool() {
-					val |= 1 << shift
-				}
-				shift += 8
-				class |= _INTEGER
-			case reflect.Pointer:
-				ok = false
-				return
-			case reflect.Int8:
-				val |= uint64(f.Int()&0xFF) << shift
-				shift += 8
-				class |= _INTEGER
-			case reflect.Int16:
-				val |= uint64(f.Int()&0xFFFF) << shift
-				shift += 16
-				class |= _INTEGER
-			case reflect.Int32:
-				val |= uint64(f.Int()&0xFFFF_FFFF) << shift
-				shift += 32
-				class |= _INTEGER
-			case reflect.Int64, reflect.Int:
-				val = uint64(f.Int())
-				shift = 64
-				class = _INTEGER
-			case reflect.Uint8:
-				val |= f.Uint() << shift
-				shift += 8
-				class |= _INTEGER
-			case reflect.Uint16:
-				val |= f.Uint() << shift
-				shift += 16
-				class |= _INTEGER
-			case reflect.Uint32:
-				val |= f.Uint() << shift
-				shift += 32
-				class |= _INTEGER
-			case reflect.Uint64, reflect.Uint, reflect.Uintptr:
-				val = f.Uint()
-				shift = 64
-				class = _INTEGER
-			case reflect.Float32:
-				val |= uint64(math.Float32bits(float32(f.Float()))) << shift
-				shift += 32
-				class |= _SSE
-			case reflect.Float64:
-				if v.Type().Size() > 16 {
-					ok = false
-					return
-				}
-				val = uint64(math.Float64bits(f.Float()))
-				shift = 64
-				class = _SSE
-			case reflect.Array:
-				place(f)
-			default:
-				panic("purego: unsupported kind " + f.Kind().String())
-			}
+// mergeClasses merges the class of every scalar in t at offset into classes.
+func mergeClasses(t reflect.Type, offset uintptr, classes *[2]int) {
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			mergeClasses(f.Type, offset+f.Offset, classes)
+		}
+	case reflect.Array:
+		for i := 0; i < t.Len(); i++ {
+			mergeClasses(t.Elem(), offset+uintptr(i)*t.Elem().Size(), classes)
+		}
+	case reflect.Float32, reflect.Float64:
+		classes[offset/8] |= _SSE
+	case reflect.Complex64, reflect.Complex128:
+		mergeClasses(complexPartsType(t), offset, classes)
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Pointer, reflect.UnsafePointer:
+		classes[offset/8] |= _INTEGER
+	default:
+		panic("purego: unsupported kind " + t.Kind().String())
+	}
+}
 
-			if shift == 64 {
-				flushIfNeeded()
-			} else if shift > 64 {
-				// Should never happen, but may if we forget to reset shift after flush (or forget to flush),
-				// better fall apart here, than corrupt arguments.
-				panic("purego: tryPlaceRegisters shift > 64")
-			}
+// unionFields returns the fields of a struct that is passed like a union of members.
+// The classes of the members are merged per eightbyte so an eightbyte that only holds
+// floats is passed in an SSE register and any other in an integer register.
+func unionFields(members []reflect.Type, size, align uintptr) []reflect.StructField {
+	if size > 2*8 || align > 8 {
+		return unionStorage(size, align)
+	}
+	var classes [2]int
+	for _, m := range members {
+		mergeClasses(m, 0, &classes)
+	}
+	var fields []reflect.StructField
+	for offset := uintptr(0); offset < size; offset += 8 {
+		chunk := size - offset
+		if chunk > 8 {
+			chunk = 8
+		}
+		var typ reflect.Type
+		switch {
+		case classes[offset/8] != _SSE:
+		case chunk == 8 && align == 8:
+			typ = reflect.TypeOf(float64(0))
+		case chunk == 8 && align == 4:
+			typ = reflect.TypeOf([2]float32{})
+		case chunk == 4:
+			typ = reflect.TypeOf(float32(0))
 		}
+		if typ == nil {
+			typ = unionStorage(chunk, align)[0].Type
+		}
+		fields = append(fields, reflect.StructField{Name: "F" + strconv.Itoa(len(fields)), Type: typ})
 	}
+	return fields
+}
 
-	place(v)
-	flushIfNeeded()
-	return ok
+// tryPlaceRegister places each eightbyte of v in the register matching its class.
+// If there are not enough free registers for all the eightbytes nothing is placed
+// and false is returned so that the whole struct can be passed in memory.
+func tryPlaceRegister(v reflect.Value, numInts, numFloats int, addFloat func(uintptr), addInt func(uintptr)) (ok bool) {
+	classes, n := classifyEightbytes(v.Type())
+	var needInts, needFloats int
+	for i := 0; i < n; i++ {
+		if classes[i] == _SSE {
+			needFloats++
+		} else {
+			needInts++
+		}
+	}
+	if numInts+needInts > numOfIntegerRegisters() || numFloats+needFloats > numOfFloatRegisters {
+		return false
+	}
+	i := 0
+	copyStruct8ByteChunks(v, func(chunk uintptr) {
+		if classes[i] == _SSE {
+			addFloat(chunk)
+		} else {
+			addInt(chunk)
+		}
+		i++
+	})
+	return true
 }
 
+// placeStack copies the memory of v onto the stack in eightbyte sized slots.
 func placeStack(v reflect.Value, addStack func(uintptr)) {
-	for i := 0; i < v.Type().NumField(); i++ {
-		f := v.Field(i)
-		switch f.Kind() {
-		case reflect.Pointer:
-			addStack(f.Pointer())
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			addStack(uintptr(f.Int()))
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-			addStack(uintptr(f.Uint()))
-		case reflect.Float32:
-			addStack(uintptr(math.Float32bits(float32(f.Float()))))
-		case reflect.Float64:
-			addStack(uintptr(math.Float64bits(f.Float())))
-		case reflect.Struct:
-			placeStack(f, addStack)
-		default:
-			panic("purego: unsupported kind " + f.Kind().String())
+	copyStruct8ByteChunks(v, addStack)
+}
+
+// copyStruct8ByteChunks passes the memory of v to addChunk in 8-byte chunks.
+// The final chunk is zero extended if the size of v is not a multiple of 8.
+func copyStruct8ByteChunks(v reflect.Value, addChunk func(uintptr)) {
+	if !v.CanAddr() {
+		addressable := reflect.New(v.Type()).Elem()
+		addressable.Set(v)
+		v = addressable
+	}
+	ptr := v.Addr().UnsafePointer()
+	size := v.Type().Size()
+	for offset := uintptr(0); offset < size; offset += 8 {
+		var chunk uintptr
+		if remaining := size - offset; remaining >= 8 {
+			chunk = *(*uintptr)(unsafe.Add(ptr, offset))
+		} else {
+			// Read byte-by-byte to avoid reading beyond allocation
+			for i := uintptr(0); i < remaining; i++ {
+				chunk |= uintptr(*(*byte)(unsafe.Add(ptr, offset+i))) << (i * 8)
+			}
 		}
+		addChunk(chunk)
 	}
 }
 
@@ -16,51 +16,32 @@ import (
 
 func getStruct(outType reflect.Type, syscall syscall15Args) (v reflect.Value) {
 	outSize := outType.Size()
-	switch {
-	case outSize == 0:
+	if outSize == 0 {
 		return reflect.New(outType).Elem()
-	case outSize <= 8:
-		r1 := syscall.a1
-		if isAllFloats, numFields := isAllSameFloat(outType); isAllFloats {
-			r1 = syscall.f1
-			if numFields == 2 {
-				r1 = syscall.f2<<32 | syscall.f1
-			}
-		}
-		return reflect.NewAt(outType, unsafe.Pointer(&struct{ a uintptr }{r1})).Elem()
-	case outSize <= 16:
-		r1, r2 := syscall.a1, syscall.a2
-		if isAllFloats, numFields := isAllSameFloat(outType); isAllFloats {
-			switch numFields {
-			case 4:
-				r1 = syscall.f2<<32 | syscall.f1
-				r2 = syscall.f4<<32 | syscall.f3
-			case 3:
-				r1 = syscall.f2<<32 | syscall.f1
-				r2 = syscall.f3
-			case 2:
-				r1 = syscall.f1
-				r2 = syscall.f2
-			default:
-				panic("unreachable")
+	}
+	if member, n, ok := hfaMembers(outType); ok {
+		// Each member of an HFA is returned in its own floating-point register (V0-V3)
+		floats := [4]uintptr{syscall.f1, syscall.f2, syscall.f3, syscall.f4}
+		var regs [4]uint64
+		if member == reflect.Float32 {
+			words := (*[8]uint32)(unsafe.Pointer(&regs))
+			for i := 0; i < n; i++ {
+				words[i] = uint32(floats[i])
 			}
-		}
-		return reflect.NewAt(outType, unsafe.Pointer(&struct{ a, b uintptr }{r1, r2})).Elem()
-	default:
-		if isAllFloats, numFields := isAllSameFloat(outType); isAllFloats && numFields <= 4 {
-			switch numFields {
-			case 4:
-				return reflect.NewAt(outType, unsafe.Pointer(&struct{ a, b, c, d uintptr }{syscall.f1, syscall.f2, syscall.f3, syscall.f4})).Elem()
-			case 3:
-				return reflect.NewAt(outType, unsafe.Pointer(&struct{ a, b, c uintptr }{syscall.f1, syscall.f2, syscall.f3})).Elem()
-			default:
-				panic("unreachable")
+		} else {
+			for i := 0; i < n; i++ {
+				regs[i] = uint64(floats[i])
 			}
 		}
-		// create struct from the Go pointer created in arm64_r8
-		// weird pointer dereference to circumvent go vet
-		return reflect.NewAt(outType, *(*unsafe.Pointer)(unsafe.Pointer(&syscall.arm64_r8))).Elem()
+		return reflect.NewAt(outType, unsafe.Pointer(&regs)).Elem()
 	}
+	if outSize <= 16 {
+		// Composites of up to 16 bytes are returned in X0 and X1 as if loaded from memory
+		return reflect.NewAt(outType, unsafe.Pointer(&struct{ a, b uintptr }{syscall.a1, syscall.a2})).Elem()
+	}
+	// create struct from the Go pointer created in arm64_r8
+	// weird pointer dereference to circumvent go vet
+	return reflect.NewAt(outType, *(*unsafe.Pointer)(unsafe.Pointer(&syscall.arm64_r8))).Elem()
 }
 
 // https://github.com/ARM-software/abi-aa/blob/main/sysvabi64/sysvabi64.rst
@@ -74,11 +55,14 @@ func addStruct(v reflect.Value, numInts, numFloats, numStack *int, addInt, addFl
 	if v.Type().Size() == 0 {
 		return keepAlive
 	}
+	if runtime.GOOS != "darwin" {
+		return addStructAAPCS64(v, numInts, numFloats, numStack, addInt, addFloat, addStack, keepAlive)
+	}
 
 	if hva, hfa, size := isHVA(v.Type()), isHFA(v.Type()), v.Type().Size(); hva || hfa || size <= 16 {
 		// if this doesn't fit entirely in registers then
 		// each element goes onto the stack
-		if hfa && *numFloats+v.NumField() > numOfFloatRegisters {
+		if hfa && *numFloats+numHFAMembers(v.Type()) > numOfFloatRegisters {
 			*numFloats = numOfFloatRegisters
 		} else if hva && *numInts+v.NumField() > numOfIntegerRegisters() {
 			*numInts = numOfIntegerRegisters()
@@ -91,6 +75,92 @@ func addStruct(v reflect.Value, numInts, numFloats, numStack *int, addInt, addFl
 	return keepAlive // the struct was allocated so don't panic
 }
 
+// addStructAAPCS64 places a struct argument following the standard AAPCS64 rules (6.8.2 in
+// [Arm64 Calling Convention]). Unlike Darwin, every argument placed on the stack takes at
+// least one 8-byte slot.
+//
+//   - An HFA is passed with one member per floating-point register. If there are not enough
+//     floating-point registers left it is copied onto the stack and no more floating-point
+//     registers are used.
+//   - Any other composite larger than 16 bytes is copied to memory and a pointer to the copy is passed.
+//   - Any other composite is loaded into consecutive integer registers as if by loading it from memory.
+//     If there are not enough integer registers left it is copied onto the stack and no more integer
+//     registers are used.
+//
+// [Arm64 Calling Convention]: https://github.com/ARM-software/abi-aa/blob/main/aapcs64/aapcs64.rst
+func addStructAAPCS64(v reflect.Value, numInts, numFloats, numStack *int, addInt, addFloat, addStack func(uintptr), keepAlive []any) []any {
+	ptr, size := structMemory(v)
+	// a composite aligned to 16 bytes starts at an even register and a 16 byte aligned stack slot
+	aligned16 := cStructAlign(v.Type()) > 8
+	addStackAligned := func() {
+		if aligned16 && *numStack%2 != 0 {
+			addStack(0)
+		}
+		copyStruct8ByteChunks(ptr, size, addStack)
+	}
+	if member, n, ok := hfaMembers(v.Type()); ok {
+		if *numFloats+n > numOfFloatRegisters {
+			*numFloats = numOfFloatRegisters
+			addStackAligned()
+			return keepAlive
+		}
+		memberSize := size / uintptr(n)
+		for i := 0; i < n; i++ {
+			if member == reflect.Float32 {
+				addFloat(uintptr(*(*uint32)(unsafe.Add(ptr, uintptr(i)*memberSize))))
+			} else {
+				addFloat(uintptr(*(*uint64)(unsafe.Add(ptr, uintptr(i)*memberSize))))
+			}
+		}
+		return keepAlive
+	}
+	if size > maxRegAllocStructSize {
+		return placeStack(v, keepAlive, addInt)
+	}
+	if aligned16 && *numInts%2 != 0 && *numInts < numOfIntegerRegisters() {
+		*numInts++
+	}
+	if *numInts+int(roundUpTo8(size)/align8ByteSize) > numOfIntegerRegisters() {
+		*numInts = numOfIntegerRegisters()
+		addStackAligned()
+		return keepAlive
+	}
+	copyStruct8ByteChunks(ptr, size, addInt)
+	return keepAlive
+}
+
+// unionFields returns the fields of a struct that is passed like a union of members.
+// A union whose members are all HFAs of the same floating-point type is itself an HFA
+// and any other union is passed like a struct of integers.
+func unionFields(members []reflect.Type, size, align uintptr) []reflect.StructField {
+	var kind reflect.Kind
+	for _, m := range members {
+		member, _, ok := hfaMembers(m)
+		if !ok || (kind != reflect.Invalid && member != kind) {
+			return unionStorage(size, align)
+		}
+		kind = member
+	}
+	elem := reflect.TypeOf(float64(0))
+	if kind == reflect.Float32 {
+		elem = reflect.TypeOf(float32(0))
+	}
+	if kind == reflect.Invalid || size%elem.Size() != 0 || size/elem.Size() > 4 {
+		return unionStorage(size, align)
+	}
+	return []reflect.StructField{{Name: "F0", Type: reflect.ArrayOf(int(size/elem.Size()), elem)}}
+}
+
+// structMemory returns a pointer to the memory of v, copying it first if v is not addressable.
+func structMemory(v reflect.Value) (ptr unsafe.Pointer, size uintptr) {
+	if !v.CanAddr() {
+		addressable := reflect.New(v.Type()).Elem()
+		addressable.Set(v)
+		v = addressable
+	}
+	return v.Addr().UnsafePointer(), v.Type().Size()
+}
+
 func placeRegisters(v reflect.Value, addFloat func(uintptr), addInt func(uintptr)) {
 	if runtime.GOOS == "darwin" {
 		placeRegistersDarwin(v, addFloat, addInt)
@@ -226,41 +296,18 @@ func placeStack(v reflect.Value, keepAlive []any, addInt func(uintptr)) []any {
 // isHFA reports a Homogeneous Floating-point Aggregate (HFA) which is a Fundamental Data Type that is a
 // Floating-Point type and at most four uniquely addressable members (5.9.5.1 in [Arm64 Calling Convention]).
 // This type of struct will be placed more compactly than the individual fields.
+// The members of nested structs and arrays are counted individually. See hfaMembers.
 //
 // [Arm64 Calling Convention]: https://github.com/ARM-software/abi-aa/blob/main/sysvabi64/sysvabi64.rst
 func isHFA(t reflect.Type) bool {
-	// round up struct size to nearest 8 see section B.4
-	structSize := roundUpTo8(t.Size())
-	if structSize == 0 || t.NumField() > 4 {
-		return false
-	}
-	first := t.Field(0)
-	switch first.Type.Kind() {
-	case reflect.Float32, reflect.Float64:
-		firstKind := first.Type.Kind()
-		for i := 0; i < t.NumField(); i++ {
-			if t.Field(i).Type.Kind() != firstKind {
-				return false
-			}
-		}
-		return true
-	case reflect.Array:
-		switch first.Type.Elem().Kind() {
-		case reflect.Float32, reflect.Float64:
-			return true
-		default:
-			return false
-		}
-	case reflect.Struct:
-		for i := 0; i < first.Type.NumField(); i++ {
-			if !isHFA(first.Type) {
-				return false
-			}
-		}
-		return true
-	default:
-		return false
-	}
+	_, _, ok := hfaMembers(t)
+	return ok
+}
+
+// numHFAMembers returns the number of floating-point registers the HFA t takes.
+func numHFAMembers(t reflect.Type) int {
+	_, n, _ := hfaMembers(t)
+	return n
 }
 
 // isHVA reports a Homogeneous Aggregate with a Fundamental Data Type that is a Short-Vector type
@@ -299,11 +346,9 @@ func isHVA(t reflect.Type) bool {
 }
 
 // copyStruct8ByteChunks copies struct memory in 8-byte chunks to the provided callback.
-// This is used for Darwin ARM64's byte-level packing of non-HFA/HVA structs.
+// This is used for Darwin ARM64's byte-level packing of non-HFA/HVA structs and for
+// composites passed in integer registers or on the stack by the standard AAPCS64.
 func copyStruct8ByteChunks(ptr unsafe.Pointer, size uintptr, addChunk func(uintptr)) {
-	if runtime.GOOS != "darwin" {
-		panic("purego: should only be called on darwin")
-	}
 	for offset := uintptr(0); offset < size; offset += 8 {
 		var chunk uintptr
 		remaining := size - offset
@@ -381,7 +426,7 @@ func shouldBundleStackArgs(v reflect.Value, numInts, numFloats int) bool {
 	}
 
 	if hfa {
-		need := v.NumField()
+		need := numHFAMembers(v.Type())
 		return numFloats+need > numOfFloatRegisters
 	}
 
@@ -407,8 +452,8 @@ func structFitsInRegisters(val reflect.Value, tempNumInts, tempNumFloats int) (b
 
 	if hfa {
 		// HFA: check if elements fit in float registers
-		if tempNumFloats+val.NumField() <= numOfFloatRegisters {
-			return true, tempNumInts, tempNumFloats + val.NumField()
+		if n := numHFAMembers(val.Type()); tempNumFloats+n <= numOfFloatRegisters {
+			return true, tempNumInts, tempNumFloats + n
 		}
 	} else if hva {
 		// HVA: check if elements fit in int registers
@@ -474,6 +519,15 @@ func collectStackArgs(args []reflect.Value, startIdx int, numInts, numFloats int
 				val = reflect.ValueOf(ptr)
 				args[startIdx+j] = val
 			}
+			if isCStrings(val.Type()) || isOut(val.Type()) {
+				var ptr unsafe.Pointer
+				if isOut(val.Type()) {
+					ptr, keepAlive = outArg(val, keepAlive)
+				} else {
+					ptr, keepAlive = cStrings(val, keepAlive)
+				}
+				val = reflect.ValueOf(ptr)
+			}
 			stackArgs = append(stackArgs, val)
 		}
 	}
@@ -0,0 +1,438 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build darwin || freebsd || linux || netbsd || windows
+
+package purego
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	stdstrings "strings"
+	"sync"
+	"unsafe"
+
+	"github.com/ebitengine/purego/internal/strings"
+)
+
+// Packed marks a struct as packed like #pragma pack(1) or __attribute__((packed)) in C when it is the
+// type of one of its fields. The fields of the struct are then laid out without any padding unless
+// they have an align tag. It takes no space.
+//
+//	type header struct {
+//		_     purego.Packed
+//		kind  uint8
+//		value uint32 // at offset 1
+//	}
+type Packed struct{}
+
+// Union marks a struct as a C union when it is the type of one of its fields. All the other fields
+// are members of the union that start at offset 0 and the union is as large as its largest member.
+// It takes no space.
+//
+// When a union is passed to C the members that are not zero are copied in order so only one of them
+// should be set. When it is returned from C every member is read from the same memory.
+//
+//	type epollData struct {
+//		_   purego.Union
+//		ptr unsafe.Pointer
+//		fd  int32
+//		u64 uint64
+//	}
+type Union struct{}
+
+// structLayout is the C layout of a Go struct that has c tags, is Packed or a Union or has string or func fields.
+// The Go struct is copied into a value of ctype when it is passed to C and back when it is returned.
+// A string field becomes a char* to a copy of the string and a func field becomes a C function pointer
+// created by NewCallback once per func value (see fieldCallback).
+type structLayout struct {
+	ctype     reflect.Type  // a Go struct with the same memory layout as the C struct
+	size      uintptr       // the size of the C struct which can be smaller than ctype.Size() if it is packed
+	align     uintptr       // the alignment of the C struct which can be larger than ctype.Align()
+	unaligned bool          // some fields are not at a multiple of their natural alignment
+	union     bool          // the fields are the members of a C union
+	fields    []layoutField // the fields to copy between the Go struct and ctype
+}
+
+// layoutField is a field of a Go struct at goOffset that is at cOffset in the C struct.
+type layoutField struct {
+	typ               reflect.Type
+	goOffset, cOffset uintptr
+	layout            *structLayout // the layout of typ if it is a struct with a C layout
+}
+
+var (
+	packedType    = reflect.TypeOf(Packed{})
+	unionType     = reflect.TypeOf(Union{})
+	structLayouts sync.Map // reflect.Type => *structLayout or nil if the C and Go layouts are the same
+	cStructTypes  sync.Map // structLayout.ctype => *structLayout
+	bytePtrType   = reflect.TypeOf((*byte)(nil))
+	uintptrType   = reflect.TypeOf(uintptr(0))
+)
+
+// structLayoutOf returns the C layout of the struct type t or nil if it is the same as its Go layout.
+func structLayoutOf(t reflect.Type) *structLayout {
+	if l, ok := structLayouts.Load(t); ok {
+		return l.(*structLayout)
+	}
+	l := newStructLayout(t)
+	if l != nil {
+		cStructTypes.Store(l.ctype, l)
+	}
+	structLayouts.Store(t, l)
+	return l
+}
+
+// cStructType returns the type with the C memory layout of the struct type t.
+func cStructType(t reflect.Type) reflect.Type {
+	if l := structLayoutOf(t); l != nil {
+		return l.ctype
+	}
+	return t
+}
+
+// cStructAlign returns the C alignment of t which is a type returned by cStructType.
+func cStructAlign(t reflect.Type) uintptr {
+	if l, ok := cStructTypes.Load(t); ok {
+		return l.(*structLayout).align
+	}
+	return uintptr(t.Align())
+}
+
+// hasUnalignedFields reports whether t, a type returned by cStructType, is a packed struct
+// with fields that are not naturally aligned.
+func hasUnalignedFields(t reflect.Type) bool {
+	if l, ok := cStructTypes.Load(t); ok {
+		return l.(*structLayout).unaligned
+	}
+	return false
+}
+
+func newStructLayout(t reflect.Type) *structLayout {
+	custom := false
+	packed := false
+	union := false
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		switch f.Type {
+		case packedType:
+			packed = true
+		case unionType:
+			union = true
+		}
+		if _, ok := f.Tag.Lookup("c"); ok || packed || union || hasCLayout(f.Type) {
+			custom = true
+		}
+	}
+	if !custom {
+		return nil
+	}
+
+	l := &structLayout{align: 1, union: union}
+	var cfields []reflect.StructField
+	var members []reflect.Type // the C types of the members of a union
+	var offset uintptr
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Type.Size() == 0 {
+			continue
+		}
+		ctype, size, align, fields, unaligned := cFieldType(f.Type)
+		if packed {
+			align = 1
+		}
+		explicitOffset, explicitAlign := parseCTag(t, f)
+		if explicitAlign > align {
+			align = explicitAlign
+		}
+		if union {
+			if explicitOffset >= 0 {
+				panic(fmt.Sprintf("purego: member %s.%s of a union can't have an offset", t, f.Name))
+			}
+			members = append(members, ctype)
+			l.fields = append(l.fields, shiftFields(fields, f.Offset, 0)...)
+			l.unaligned = l.unaligned || unaligned
+			if align > l.align {
+				l.align = align
+			}
+			if size > offset {
+				offset = size
+			}
+			continue
+		}
+		fieldOffset := (offset + align - 1) &^ (align - 1)
+		if explicitOffset >= 0 {
+			if uintptr(explicitOffset) < offset {
+				panic(fmt.Sprintf("purego: field %s.%s at offset %d overlaps the previous field", t, f.Name, explicitOffset))
+			}
+			fieldOffset = uintptr(explicitOffset)
+		}
+		if fieldOffset%uintptr(ctype.Align()) != 0 {
+			for _, field := range fields {
+				if hasPointers(field.typ) {
+					panic(fmt.Sprintf("purego: field %s.%s contains pointers and must be aligned", t, f.Name))
+				}
+			}
+			// Go can't describe an unaligned field so keep its memory in an array of bytes.
+			ctype = byteArray(size)
+			unaligned = true
+		}
+		if fieldOffset > offset {
+			cfields = append(cfields, paddingField(len(cfields), fieldOffset-offset))
+		}
+		cfields = append(cfields, reflect.StructField{Name: "F" + strconv.Itoa(len(cfields)), Type: ctype})
+		l.fields = append(l.fields, shiftFields(fields, f.Offset, fieldOffset)...)
+		l.unaligned = l.unaligned || unaligned
+		if align > l.align {
+			l.align = align
+		}
+		offset = fieldOffset + size
+	}
+	l.size = (offset + l.align - 1) &^ (l.align - 1)
+	if union {
+		// Go can't describe overlapping fields so the union is replaced by fields
+		// that are passed the same way as the merged members
+		cfields = unionFields(members, l.size, l.align)
+	} else if l.size > offset {
+		cfields = append(cfields, paddingField(len(cfields), l.size-offset))
+	}
+	if len(cfields) > 0 {
+		// make the type unique for its alignment so that cStructTypes can tell different layouts apart
+		cfields[0].Tag = reflect.StructTag(fmt.Sprintf(`purego:"align=%d,unaligned=%t"`, l.align, l.unaligned))
+	}
+	l.ctype = reflect.StructOf(cfields)
+	return l
+}
+
+// shiftFields returns a copy of fields moved to goOffset and cOffset.
+func shiftFields(fields []layoutField, goOffset, cOffset uintptr) []layoutField {
+	shifted := make([]layoutField, len(fields))
+	for i, field := range fields {
+		field.goOffset += goOffset
+		field.cOffset += cOffset
+		shifted[i] = field
+	}
+	return shifted
+}
+
+// unionStorage returns fields of unsigned integers as large as the alignment of a union
+// that fill size bytes. The union is then passed like a struct of integers.
+func unionStorage(size, align uintptr) []reflect.StructField {
+	if size == 0 {
+		return nil
+	}
+	if align > 8 {
+		align = 8
+	}
+	var unit reflect.Type
+	switch align {
+	case 1:
+		unit = reflect.TypeOf(uint8(0))
+	case 2:
+		unit = reflect.TypeOf(uint16(0))
+	case 4:
+		unit = reflect.TypeOf(uint32(0))
+	default:
+		unit = reflect.TypeOf(uint64(0))
+	}
+	return []reflect.StructField{{Name: "F0", Type: reflect.ArrayOf(int(size/align), unit)}}
+}
+
+// hasCLayout reports whether the C memory of t differs from its Go memory. That is a struct with
+// a C layout, a string or a func which are converted to pointers or an array of those.
+func hasCLayout(t reflect.Type) bool {
+	for t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Func:
+		return true
+	case reflect.Struct:
+		return structLayoutOf(t) != nil
+	}
+	return false
+}
+
+// cFieldType returns the type with the C memory layout of a field of type t, its C size and alignment,
+// the fields to copy relative to the start of the field and whether some of them are unaligned.
+// The size of ctype is the C size so that the following fields can be placed right after it.
+func cFieldType(t reflect.Type) (ctype reflect.Type, size, align uintptr, fields []layoutField, unaligned bool) {
+	switch t.Kind() {
+	case reflect.Struct:
+		if l := structLayoutOf(t); l != nil {
+			ctype = l.ctype
+			if ctype.Size() != l.size {
+				// a packed struct without trailing padding
+				ctype = byteArray(l.size)
+			}
+			return ctype, l.size, l.align, []layoutField{{typ: t, layout: l}}, l.unaligned
+		}
+	case reflect.String:
+		return bytePtrType, bytePtrType.Size(), uintptr(bytePtrType.Align()), []layoutField{{typ: t}}, false
+	case reflect.Func:
+		return uintptrType, uintptrType.Size(), uintptr(uintptrType.Align()), []layoutField{{typ: t}}, false
+	case reflect.Array:
+		if !hasCLayout(t) {
+			break
+		}
+		elem, elemSize, align, elemFields, unaligned := cFieldType(t.Elem())
+		for i := 0; i < t.Len(); i++ {
+			fields = append(fields, shiftFields(elemFields, uintptr(i)*t.Elem().Size(), uintptr(i)*elemSize)...)
+		}
+		size = uintptr(t.Len()) * elemSize
+		if elem.Size() != elemSize {
+			// the elements after the first one are not aligned
+			return byteArray(size), size, align, fields, unaligned || t.Len() > 1
+		}
+		return reflect.ArrayOf(t.Len(), elem), size, align, fields, unaligned
+	}
+	return t, t.Size(), uintptr(t.Align()), []layoutField{{typ: t}}, false
+}
+
+// parseCTag returns the offset and the alignment of the c tag of f or -1 and 0 if they are not set.
+func parseCTag(t reflect.Type, f reflect.StructField) (offset int, align uintptr) {
+	offset = -1
+	tag, ok := f.Tag.Lookup("c")
+	if !ok {
+		return offset, 0
+	}
+	for _, opt := range stdstrings.Split(tag, ",") {
+		key, value, _ := stdstrings.Cut(opt, "=")
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			panic(fmt.Sprintf("purego: invalid c tag %q of field %s.%s", tag, t, f.Name))
+		}
+		switch key {
+		case "offset":
+			offset = n
+		case "align":
+			if n == 0 || n&(n-1) != 0 {
+				panic(fmt.Sprintf("purego: alignment of field %s.%s must be a power of two", t, f.Name))
+			}
+			align = uintptr(n)
+		default:
+			panic(fmt.Sprintf("purego: invalid c tag %q of field %s.%s", tag, t, f.Name))
+		}
+	}
+	return offset, align
+}
+
+func paddingField(i int, size uintptr) reflect.StructField {
+	return reflect.StructField{Name: "F" + strconv.Itoa(i), Type: byteArray(size)}
+}
+
+func byteArray(size uintptr) reflect.Type {
+	return reflect.ArrayOf(int(size), reflect.TypeOf(byte(0)))
+}
+
+func hasPointers(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.UnsafePointer, reflect.String, reflect.Slice, reflect.Map, reflect.Chan, reflect.Func, reflect.Interface:
+		return true
+	case reflect.Array:
+		return t.Len() > 0 && hasPointers(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if hasPointers(t.Field(i).Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// toC returns a copy of v, a value of the Go struct, with the C memory layout.
+func (l *structLayout) toC(v reflect.Value) reflect.Value {
+	if !v.CanAddr() {
+		addressable := reflect.New(v.Type()).Elem()
+		addressable.Set(v)
+		v = addressable
+	}
+	c := reflect.New(l.ctype).Elem()
+	l.copyFields(c.Addr().UnsafePointer(), v.Addr().UnsafePointer(), true)
+	return c
+}
+
+// fromC returns a value of the Go struct t copied from c which has the C memory layout.
+func (l *structLayout) fromC(c reflect.Value, t reflect.Type) reflect.Value {
+	if !c.CanAddr() {
+		addressable := reflect.New(c.Type()).Elem()
+		addressable.Set(c)
+		c = addressable
+	}
+	v := reflect.New(t).Elem()
+	l.copyFields(c.Addr().UnsafePointer(), v.Addr().UnsafePointer(), false)
+	return v
+}
+
+// fieldCallbacks holds the C function pointers of func fields by the closure of the func value.
+var fieldCallbacks struct {
+	lock sync.Mutex
+	ptrs map[unsafe.Pointer]uintptr
+}
+
+// fieldCallback returns the C function pointer of fn, the addressable value of a func field.
+// Callbacks can't be released while C may still hold the pointer so every func value gets one
+// callback that is reused whenever it is passed again. A func value that is created for every call,
+// like a closure over local variables, uses up a callback each time.
+func fieldCallback(fn reflect.Value) uintptr {
+	closure := *(*unsafe.Pointer)(fn.Addr().UnsafePointer())
+	fieldCallbacks.lock.Lock()
+	defer fieldCallbacks.lock.Unlock()
+	if cfn, ok := fieldCallbacks.ptrs[closure]; ok {
+		return cfn
+	}
+	if fieldCallbacks.ptrs == nil {
+		fieldCallbacks.ptrs = make(map[unsafe.Pointer]uintptr)
+	}
+	cfn := NewCallback(fn.Interface())
+	fieldCallbacks.ptrs[closure] = cfn
+	return cfn
+}
+
+func (l *structLayout) copyFields(cptr, goptr unsafe.Pointer, toC bool) {
+	for _, f := range l.fields {
+		c := unsafe.Add(cptr, f.cOffset)
+		g := unsafe.Add(goptr, f.goOffset)
+		if l.union && toC && reflect.NewAt(f.typ, g).Elem().IsZero() {
+			// only the members that are set are copied into the union
+			continue
+		}
+		if f.layout != nil {
+			f.layout.copyFields(c, g, toC)
+			continue
+		}
+		switch f.typ.Kind() {
+		case reflect.String:
+			if toC {
+				// the C string is kept alive by the C struct for as long as it is used
+				reflect.NewAt(bytePtrType, c).Elem().Set(reflect.ValueOf(strings.CString(*(*string)(g))))
+			} else {
+				reflect.NewAt(f.typ, g).Elem().SetString(strings.GoString(*(*uintptr)(c)))
+			}
+			continue
+		case reflect.Func:
+			fn := reflect.NewAt(f.typ, g).Elem()
+			if toC {
+				if !fn.IsNil() {
+					*(*uintptr)(c) = fieldCallback(fn)
+				}
+			} else if cfn := *(*uintptr)(c); cfn != 0 {
+				// wrap the C function in a Go function of the type of the field
+				RegisterFunc(fn.Addr().Interface(), cfn)
+			}
+			continue
+		}
+		dst, src := g, c
+		if toC {
+			dst, src = c, g
+		}
+		if hasPointers(f.typ) {
+			// use reflect so that the write barriers of the garbage collector run
+			reflect.NewAt(f.typ, dst).Elem().Set(reflect.NewAt(f.typ, src).Elem())
+			continue
+		}
+		copy(unsafe.Slice((*byte)(dst), f.typ.Size()), unsafe.Slice((*byte)(src), f.typ.Size()))
+	}
+}
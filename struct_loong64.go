@@ -211,3 +211,9 @@ func collectStackArgs(args []reflect.Value, startIdx int, numInts, numFloats int
 func bundleStackArgs(stackArgs []reflect.Value, addStack func(uintptr)) {
 	panic("purego: bundleStackArgs should not be called on loong64")
 }
+
+// unionFields returns the fields of a struct that is passed like a union of members.
+// On loong64 a union is passed like a struct of integers.
+func unionFields(members []reflect.Type, size, align uintptr) []reflect.StructField {
+	return unionStorage(size, align)
+}
@@ -1,7 +1,7 @@
 // SPDX-License-Identifier: Apache-2.0
 // SPDX-FileCopyrightText: 2024 The Ebitengine Authors
 
-//go:build darwin && (arm64 || amd64)
+//go:build (darwin || linux) && (arm64 || amd64)
 
 package purego_test
 
@@ -499,6 +499,214 @@ func TestRegisterFunc_structArgs(t *testing.T) {
 			t.Fatalf("FourInt32s returned %d wanted %d", result, want)
 		}
 	}
+	{
+		type CharInt struct {
+			a int8
+			b int32
+		}
+		var CharIntFn func(CharInt) int32
+		purego.RegisterLibFunc(&CharIntFn, lib, "CharInt")
+		if ret := CharIntFn(CharInt{a: -3, b: -120}); ret != expectedSigned {
+			t.Fatalf("CharInt returned %d wanted %d", ret, expectedSigned)
+		}
+	}
+	{
+		type IntLong struct {
+			a int32
+			b int64
+		}
+		var IntLongFn func(IntLong) int64
+		purego.RegisterLibFunc(&IntLongFn, lib, "IntLong")
+		if ret := IntLongFn(IntLong{a: 0xBEEF, b: 0xDEAD0000}); ret != expectedUnsigned {
+			t.Fatalf("IntLong returned %#x wanted %#x", ret, expectedUnsigned)
+		}
+	}
+	{
+		type SixInt32s struct {
+			a, b, c, d, e, f int32
+		}
+		var SixInt32sFn func(SixInt32s) int32
+		purego.RegisterLibFunc(&SixInt32sFn, lib, "SixInt32s")
+		if ret := SixInt32sFn(SixInt32s{1, 2, 3, 4, 5, -138}); ret != expectedSigned {
+			t.Fatalf("SixInt32s returned %d wanted %d", ret, expectedSigned)
+		}
+	}
+	{
+		type TwoInt64s struct {
+			x, y int64
+		}
+		var TwoInt64sAfterFiveInts func(a, b, c, d, e int64, s TwoInt64s, f int64) int64
+		purego.RegisterLibFunc(&TwoInt64sAfterFiveInts, lib, "TwoInt64sAfterFiveInts")
+		if ret := TwoInt64sAfterFiveInts(0xD0000000, 0xE000000, 0xA00000, 0xD0000, 0xB000, TwoInt64s{0xE00, 0x100}, 0x1EF); ret != expectedUnsigned {
+			t.Fatalf("TwoInt64sAfterFiveInts returned %#x wanted %#x", ret, expectedUnsigned)
+		}
+	}
+	{
+		type DoubleAndInt64 struct {
+			x float64
+			y int64
+		}
+		var DoubleAndInt64AfterEightDoubles func(a, b, c, d, e, f, g, h float64, s DoubleAndInt64) float64
+		purego.RegisterLibFunc(&DoubleAndInt64AfterEightDoubles, lib, "DoubleAndInt64AfterEightDoubles")
+		if ret := DoubleAndInt64AfterEightDoubles(1, 1, 1, 1, 1, 1, 1, 1, DoubleAndInt64{x: -3, y: 5}); ret != expectedDouble {
+			t.Fatalf("DoubleAndInt64AfterEightDoubles returned %f wanted %f", ret, expectedDouble)
+		}
+	}
+	{
+		type PackedCharInt32Double struct {
+			_ purego.Packed
+			a int8
+			b int32
+			c float64
+		}
+		var PackedCharInt32DoubleFn func(PackedCharInt32Double) int64
+		purego.RegisterLibFunc(&PackedCharInt32DoubleFn, lib, "PackedCharInt32Double")
+		if ret := PackedCharInt32DoubleFn(PackedCharInt32Double{a: -3, b: -200, c: 80}); ret != expectedSigned {
+			t.Fatalf("PackedCharInt32Double returned %d wanted %d", ret, expectedSigned)
+		}
+	}
+	{
+		type PackedFloats struct {
+			_ purego.Packed
+			a float32
+			b float32
+			c int8
+		}
+		var PackedFloatsFn func(PackedFloats) float32
+		purego.RegisterLibFunc(&PackedFloatsFn, lib, "PackedFloats")
+		if ret := PackedFloatsFn(PackedFloats{a: 2, b: 3, c: 5}); ret != expectedFloat {
+			t.Fatalf("PackedFloats returned %f wanted %f", ret, expectedFloat)
+		}
+	}
+	{
+		type ExplicitOffset struct {
+			a int32
+			b int32 `c:"offset=12"`
+		}
+		var ExplicitOffsetFn func(ExplicitOffset) int32
+		purego.RegisterLibFunc(&ExplicitOffsetFn, lib, "ExplicitOffset")
+		if ret := ExplicitOffsetFn(ExplicitOffset{a: 2, b: 125}); ret != expectedSigned {
+			t.Fatalf("ExplicitOffset returned %d wanted %d", ret, expectedSigned)
+		}
+	}
+	{
+		type Aligned16 struct {
+			a int64 `c:"align=16"`
+			b int64
+		}
+		var Aligned16AfterInt func(int64, Aligned16) int64
+		purego.RegisterLibFunc(&Aligned16AfterInt, lib, "Aligned16AfterInt")
+		if ret := Aligned16AfterInt(100, Aligned16{a: 7, b: 230}); ret != expectedSigned {
+			t.Fatalf("Aligned16AfterInt returned %d wanted %d", ret, expectedSigned)
+		}
+	}
+	{
+		type OverAligned struct {
+			a int64
+			b int64 `c:"align=16"`
+			c int64
+		}
+		var OverAlignedAfterSevenInts func(a, b, c, d, e, f, g int64, s OverAligned) int64
+		purego.RegisterLibFunc(&OverAlignedAfterSevenInts, lib, "OverAlignedAfterSevenInts")
+		if ret := OverAlignedAfterSevenInts(1, 2, 3, 4, 5, 6, 7, OverAligned{a: 10, b: 200, c: 39}); ret != expectedSigned {
+			t.Fatalf("OverAlignedAfterSevenInts returned %d wanted %d", ret, expectedSigned)
+		}
+	}
+	{
+		type IntOrDouble struct {
+			_ purego.Union
+			i int64
+			d float64
+		}
+		var UnionIntOrDouble func(float64, IntOrDouble) int64
+		purego.RegisterLibFunc(&UnionIntOrDouble, lib, "UnionIntOrDouble")
+		if ret := UnionIntOrDouble(3, IntOrDouble{i: -126}); ret != expectedSigned {
+			t.Fatalf("UnionIntOrDouble returned %d wanted %d", ret, expectedSigned)
+		}
+	}
+	{
+		type FloatOrFloats struct {
+			_  purego.Union
+			f  float32
+			fs [2]float32
+		}
+		var UnionFloats func(FloatOrFloats) float32
+		purego.RegisterLibFunc(&UnionFloats, lib, "UnionFloats")
+		if ret := UnionFloats(FloatOrFloats{fs: [2]float32{3, 7}}); ret != expectedFloat {
+			t.Fatalf("UnionFloats returned %f wanted %f", ret, expectedFloat)
+		}
+	}
+	{
+		type Event struct {
+			typ  int32
+			data struct {
+				_   purego.Union
+				key int32
+				x   float32
+			}
+			time float64
+		}
+		var EventKey func(Event) float64
+		purego.RegisterLibFunc(&EventKey, lib, "EventKey")
+		e := Event{typ: 1, time: 7}
+		e.data.key = 2
+		if ret := EventKey(e); ret != expectedDouble {
+			t.Fatalf("EventKey returned %f wanted %f", ret, expectedDouble)
+		}
+	}
+	{
+		type Named struct {
+			name    string
+			id      int32
+			enabled bool
+		}
+		var NamedLength func(Named) int64
+		purego.RegisterLibFunc(&NamedLength, lib, "NamedLength")
+		if ret := NamedLength(Named{name: "purego", id: 7, enabled: true}); ret != 42 {
+			t.Fatalf("NamedLength returned %d wanted %d", ret, 42)
+		}
+	}
+	{
+		type Ops struct {
+			apply func(int64) int64
+			x     int64
+		}
+		var ApplyOps func(Ops) int64
+		purego.RegisterLibFunc(&ApplyOps, lib, "ApplyOps")
+		ops := Ops{apply: func(x int64) int64 { return x + 1 }, x: expectedSigned - 1}
+		if ret := ApplyOps(ops); ret != expectedSigned {
+			t.Fatalf("ApplyOps returned %d wanted %d", ret, expectedSigned)
+		}
+		// the callback of a func value is created once
+		used, _ := purego.CallbackSlots()
+		for i := 0; i < 10; i++ {
+			ApplyOps(ops)
+		}
+		if after, _ := purego.CallbackSlots(); after != used {
+			t.Fatalf("passing the same func field 10 times used %d more callbacks", after-used)
+		}
+	}
+	{
+		type Point struct{ x, y float32 }
+		type Line struct{ pts [2]Point }
+		var LineSum func(Line) float32
+		purego.RegisterLibFunc(&LineSum, lib, "LineSum")
+		if ret := LineSum(Line{[2]Point{{1, 2}, {3, 4}}}); ret != expectedFloat {
+			t.Fatalf("LineSum returned %f wanted %f", ret, expectedFloat)
+		}
+	}
+	{
+		type Flag struct {
+			set   bool
+			value int16
+		}
+		type Flags struct{ flags [3]Flag }
+		var FlagsSum func(Flags) int64
+		purego.RegisterLibFunc(&FlagsSum, lib, "FlagsSum")
+		if ret := FlagsSum(Flags{[3]Flag{{true, -100}, {false, 5}, {true, -23}}}); ret != expectedSigned {
+			t.Fatalf("FlagsSum returned %d wanted %d", ret, expectedSigned)
+		}
+	}
 }
 
 func TestRegisterFunc_structReturns(t *testing.T) {
@@ -772,4 +980,133 @@ func TestRegisterFunc_structReturns(t *testing.T) {
 		runtime.KeepAlive(a)
 		runtime.KeepAlive(b)
 	}
+	{
+		type CharInt struct {
+			a int8
+			b int32
+		}
+		var ReturnCharInt func(a int8, b int32) CharInt
+		purego.RegisterLibFunc(&ReturnCharInt, lib, "ReturnCharInt")
+		expected := CharInt{-1, 2}
+		if ret := ReturnCharInt(-1, 2); ret != expected {
+			t.Fatalf("ReturnCharInt returned %+v wanted %+v", ret, expected)
+		}
+	}
+	{
+		type IntLong struct {
+			a int32
+			b int64
+		}
+		var ReturnIntLong func(a int32, b int64) IntLong
+		purego.RegisterLibFunc(&ReturnIntLong, lib, "ReturnIntLong")
+		expected := IntLong{1, -2}
+		if ret := ReturnIntLong(1, -2); ret != expected {
+			t.Fatalf("ReturnIntLong returned %+v wanted %+v", ret, expected)
+		}
+	}
+	{
+		type IntDouble struct {
+			a int32
+			b float64
+		}
+		var ReturnIntDouble func(a int32, b float64) IntDouble
+		purego.RegisterLibFunc(&ReturnIntDouble, lib, "ReturnIntDouble")
+		expected := IntDouble{1, 2}
+		if ret := ReturnIntDouble(1, 2); ret != expected {
+			t.Fatalf("ReturnIntDouble returned %+v wanted %+v", ret, expected)
+		}
+	}
+	{
+		type PackedCharInt struct {
+			_ purego.Packed
+			a int8
+			b int32
+		}
+		var ReturnPackedCharInt func(a int8, b int32) PackedCharInt
+		purego.RegisterLibFunc(&ReturnPackedCharInt, lib, "ReturnPackedCharInt")
+		expected := PackedCharInt{a: -1, b: 0x12345678}
+		if ret := ReturnPackedCharInt(-1, 0x12345678); ret != expected {
+			t.Fatalf("ReturnPackedCharInt returned %+v wanted %+v", ret, expected)
+		}
+	}
+	{
+		type ExplicitOffset struct {
+			a int16
+			b int32 `c:"offset=12"`
+			c float64
+		}
+		var ReturnExplicitOffset func(a int16, b int32, c float64) ExplicitOffset
+		purego.RegisterLibFunc(&ReturnExplicitOffset, lib, "ReturnExplicitOffset")
+		expected := ExplicitOffset{a: 1, b: -2, c: 3}
+		if ret := ReturnExplicitOffset(1, -2, 3); ret != expected {
+			t.Fatalf("ReturnExplicitOffset returned %+v wanted %+v", ret, expected)
+		}
+	}
+	{
+		type IntOrDouble struct {
+			_ purego.Union
+			i int64
+			d float64
+		}
+		var ReturnUnionDouble func(d float64) IntOrDouble
+		purego.RegisterLibFunc(&ReturnUnionDouble, lib, "ReturnUnionDouble")
+		expected := IntOrDouble{i: int64(math.Float64bits(1.5)), d: 1.5}
+		if ret := ReturnUnionDouble(1.5); ret != expected {
+			t.Fatalf("ReturnUnionDouble returned %+v wanted %+v", ret, expected)
+		}
+	}
+	{
+		type FloatOrFloats struct {
+			_  purego.Union
+			f  float32
+			fs [2]float32
+		}
+		var ReturnUnionFloats func(a, b float32) FloatOrFloats
+		purego.RegisterLibFunc(&ReturnUnionFloats, lib, "ReturnUnionFloats")
+		expected := FloatOrFloats{f: 2, fs: [2]float32{2, 3}}
+		if ret := ReturnUnionFloats(2, 3); ret != expected {
+			t.Fatalf("ReturnUnionFloats returned %+v wanted %+v", ret, expected)
+		}
+	}
+	{
+		type Large struct {
+			_ purego.Union
+			a [3]int64
+			d float64
+		}
+		var ReturnUnionLarge func(a, b, c int64) Large
+		purego.RegisterLibFunc(&ReturnUnionLarge, lib, "ReturnUnionLarge")
+		expected := Large{a: [3]int64{1, 2, 3}, d: math.Float64frombits(1)}
+		if ret := ReturnUnionLarge(1, 2, 3); ret != expected {
+			t.Fatalf("ReturnUnionLarge returned %+v wanted %+v", ret, expected)
+		}
+	}
+	{
+		type Named struct {
+			name    string
+			id      int32
+			enabled bool
+		}
+		var ReturnNamed func(id int32) Named
+		purego.RegisterLibFunc(&ReturnNamed, lib, "ReturnNamed")
+		expected := Named{name: "purego", id: 5, enabled: true}
+		if ret := ReturnNamed(5); ret != expected {
+			t.Fatalf("ReturnNamed returned %+v wanted %+v", ret, expected)
+		}
+	}
+	{
+		type Ops struct {
+			apply func(int64) int64
+			x     int64
+		}
+		var ReturnOps func(x int64) Ops
+		purego.RegisterLibFunc(&ReturnOps, lib, "ReturnOps")
+		ret := ReturnOps(21)
+		if ret.apply == nil || ret.x != 21 {
+			t.Fatalf("ReturnOps returned %+v wanted a function and x 21", ret)
+		}
+		if got := ret.apply(ret.x); got != 42 {
+			t.Fatalf("ReturnOps().apply(21) returned %d wanted %d", got, 42)
+		}
+	}
 }
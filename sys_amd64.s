@@ -8,8 +8,10 @@
 #include "go_asm.h"
 #include "funcdata.h"
 
-#define STACK_SIZE 80
-#define PTR_ADDRESS (STACK_SIZE - 8)
+#define LOCALS_SIZE 16
+#define PTR_ADDRESS -8
+#define ERRNO_ADDRESS -16
+#define NUM_STACK_REGS 9 // a7-a15
 
 // syscall15X calls a function in libc on behalf of the syscall package.
 // syscall15X takes a pointer to a struct like:
@@ -30,10 +32,15 @@
 //	a13    uintptr
 //	a14    uintptr
 //	a15    uintptr
-//	r1    uintptr
-//	r2    uintptr
-//	err   uintptr
+//	f1-f8    uintptr
+//	arm64_r8    uintptr
+//	err    uintptr
+//	stackArgs    uintptr
+//	numStackArgs    uintptr
+//	amd64_x87    uintptr
+//	captureErrno    uintptr
 // }
+// a7-a15 are pushed onto the stack followed by the numStackArgs words at stackArgs.
 // syscall15X must be called on the g0 stack with the
 // C calling convention (use libcCall).
 GLOBL ·syscall15XABI0(SB), NOPTR|RODATA, $8
@@ -41,25 +48,31 @@ DATA ·syscall15XABI0(SB)/8, $syscall15X(SB)
 TEXT syscall15X(SB), NOSPLIT|NOFRAME, $0
 	PUSHQ BP
 	MOVQ  SP, BP
-	SUBQ  $STACK_SIZE, SP
+	SUBQ  $LOCALS_SIZE, SP
 	MOVQ  DI, PTR_ADDRESS(BP) // save the pointer
-	MOVQ  DI, R11
 
-	MOVQ syscall15Args_f1(R11), X0 // f1
-	MOVQ syscall15Args_f2(R11), X1 // f2
-	MOVQ syscall15Args_f3(R11), X2 // f3
-	MOVQ syscall15Args_f4(R11), X3 // f4
-	MOVQ syscall15Args_f5(R11), X4 // f5
-	MOVQ syscall15Args_f6(R11), X5 // f6
-	MOVQ syscall15Args_f7(R11), X6 // f7
-	MOVQ syscall15Args_f8(R11), X7 // f8
-
-	MOVQ syscall15Args_a1(R11), DI // a1
-	MOVQ syscall15Args_a2(R11), SI // a2
-	MOVQ syscall15Args_a3(R11), DX // a3
-	MOVQ syscall15Args_a4(R11), CX // a4
-	MOVQ syscall15Args_a5(R11), R8 // a5
-	MOVQ syscall15Args_a6(R11), R9 // a6
+	// clear errno and save its address if captureErrno is set
+	MOVQ  $0, ERRNO_ADDRESS(BP)
+	MOVQ  syscall15Args_captureErrno(DI), R10
+	TESTQ R10, R10
+	JZ    noerrno
+	MOVQ  ·errnoLocationABI0(SB), R10
+	TESTQ R10, R10
+	JZ    noerrno
+	CALL  R10
+	MOVL  $0, (AX)
+	MOVQ  AX, ERRNO_ADDRESS(BP)
+
+noerrno:
+	MOVQ PTR_ADDRESS(BP), R11
+
+	// make room for the stack arguments keeping SP 16 byte aligned
+	MOVQ syscall15Args_numStackArgs(R11), CX
+	MOVQ CX, AX
+	SHLQ $3, AX
+	ADDQ $(NUM_STACK_REGS*8+15), AX
+	ANDQ $~15, AX
+	SUBQ AX, SP
 
 	// push the remaining paramters onto the stack
 	MOVQ syscall15Args_a7(R11), R12
@@ -80,7 +93,38 @@ TEXT syscall15X(SB), NOSPLIT|NOFRAME, $0
 	MOVQ R12, 56(SP)                 // push a14
 	MOVQ syscall15Args_a15(R11), R12
 	MOVQ R12, 64(SP)                 // push a15
-	XORL AX, AX                      // vararg: say "no float args"
+
+	// push the stack arguments after a15
+	MOVQ syscall15Args_stackArgs(R11), SI
+	LEAQ (NUM_STACK_REGS*8)(SP), DI
+	TESTQ CX, CX
+	JZ    pushed
+
+push:
+	MOVQ (SI), R12
+	MOVQ R12, (DI)
+	ADDQ $8, SI
+	ADDQ $8, DI
+	DECQ CX
+	JNZ  push
+
+pushed:
+	MOVQ syscall15Args_f1(R11), X0 // f1
+	MOVQ syscall15Args_f2(R11), X1 // f2
+	MOVQ syscall15Args_f3(R11), X2 // f3
+	MOVQ syscall15Args_f4(R11), X3 // f4
+	MOVQ syscall15Args_f5(R11), X4 // f5
+	MOVQ syscall15Args_f6(R11), X5 // f6
+	MOVQ syscall15Args_f7(R11), X6 // f7
+	MOVQ syscall15Args_f8(R11), X7 // f8
+
+	MOVQ syscall15Args_a1(R11), DI // a1
+	MOVQ syscall15Args_a2(R11), SI // a2
+	MOVQ syscall15Args_a3(R11), DX // a3
+	MOVQ syscall15Args_a4(R11), CX // a4
+	MOVQ syscall15Args_a5(R11), R8 // a5
+	MOVQ syscall15Args_a6(R11), R9 // a6
+	MOVL $8, AX                    // vararg: upper bound of vector registers used
 
 	MOVQ syscall15Args_fn(R11), R10 // fn
 	CALL R10
@@ -91,8 +135,23 @@ TEXT syscall15X(SB), NOSPLIT|NOFRAME, $0
 	MOVQ X0, syscall15Args_f1(DI) // f1
 	MOVQ X1, syscall15Args_f2(DI) // f2
 
-	XORL AX, AX          // no error (it's ignored anyway)
-	ADDQ $STACK_SIZE, SP
+	// a long double is returned on the x87 stack which must be empty again after the call
+	MOVQ  syscall15Args_amd64_x87(DI), R10
+	TESTQ R10, R10
+	JZ    nox87
+	FMOVXP F0, syscall15Args_f1(DI)
+
+nox87:
+	// read errno right after the call
+	MOVQ    ERRNO_ADDRESS(BP), R10
+	TESTQ   R10, R10
+	JZ      done
+	MOVLQSX (R10), R10
+	MOVQ    R10, syscall15Args_err(DI)
+
+done:
+	XORL AX, AX           // no error (it's ignored anyway)
+	ADDQ $LOCALS_SIZE, SP
 	MOVQ BP, SP
 	POPQ BP
 	RET
@@ -137,10 +196,11 @@ TEXT callbackasm1(SB), NOSPLIT|NOFRAME, $0
 	// the "frame" to cgocallback and on to callbackWrap.
 	// $24 to make enough room for the arguments to runtime.cgocallback
 	SUBQ $(24+callbackArgs__size), SP
-	MOVQ AX, (24+callbackArgs_index)(SP)  // callback index
-	MOVQ R8, (24+callbackArgs_args)(SP)   // address of args vector
-	MOVQ $0, (24+callbackArgs_result)(SP) // result
-	LEAQ 24(SP), AX                       // take the address of callbackArgs
+	MOVQ AX, (24+callbackArgs_index)(SP)       // callback index
+	MOVQ R8, (24+callbackArgs_args)(SP)        // address of args vector
+	MOVQ $0, (24+callbackArgs_result)(SP)      // result
+	MOVQ $0, (24+callbackArgs_floatResult)(SP) // floating-point result
+	LEAQ 24(SP), AX                            // take the address of callbackArgs
 
 	// Call cgocallback, which will call callbackWrap(frame).
 	MOVQ ·callbackWrap_call(SB), DI // Get the ABIInternal function pointer
@@ -151,7 +211,10 @@ TEXT callbackasm1(SB), NOSPLIT|NOFRAME, $0
 	CALL crosscall2(SB) // runtime.cgocallback(fn, frame, ctxt uintptr)
 
 	// Get callback result.
-	MOVQ (24+callbackArgs_result)(SP), AX
+	MOVQ  (24+callbackArgs_result)(SP), AX
+	MOVQ  (24+callbackArgs_result2)(SP), DX
+	MOVSD (24+callbackArgs_floatResult)(SP), X0
+	MOVSD (24+callbackArgs_floatResult+8)(SP), X1
 	ADDQ $(24+callbackArgs__size), SP     // remove callbackArgs struct
 
 	POP_REGS_HOST_TO_ABI0()
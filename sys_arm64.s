@@ -7,8 +7,11 @@
 #include "go_asm.h"
 #include "funcdata.h"
 
-#define STACK_SIZE 64
-#define PTR_ADDRESS (STACK_SIZE - 8)
+#define LOCALS_SIZE 32
+#define PTR_ADDRESS (LOCALS_SIZE - 8)
+#define ERRNO_ADDRESS (LOCALS_SIZE - 16)
+#define R19_ADDRESS (LOCALS_SIZE - 24)
+#define NUM_STACK_REGS 7 // a9-a15
 
 // syscall15X calls a function in libc on behalf of the syscall package.
 // syscall15X takes a pointer to a struct like:
@@ -29,19 +32,76 @@
 //	a13    uintptr
 //	a14    uintptr
 //	a15    uintptr
-//	r1    uintptr
-//	r2    uintptr
-//	err   uintptr
+//	f1-f8    uintptr
+//	arm64_r8    uintptr
+//	err    uintptr
+//	stackArgs    uintptr
+//	numStackArgs    uintptr
+//	amd64_x87    uintptr
+//	captureErrno    uintptr
 // }
+// a9-a15 are pushed onto the stack followed by the numStackArgs words at stackArgs.
 // syscall15X must be called on the g0 stack with the
 // C calling convention (use libcCall).
 GLOBL ·syscall15XABI0(SB), NOPTR|RODATA, $8
 DATA ·syscall15XABI0(SB)/8, $syscall15X(SB)
 TEXT syscall15X(SB), NOSPLIT, $0
-	SUB  $STACK_SIZE, RSP     // push structure pointer
+	SUB  $LOCALS_SIZE, RSP     // push structure pointer
 	MOVD R0, PTR_ADDRESS(RSP)
-	MOVD R0, R9
+	MOVD R19, R19_ADDRESS(RSP) // R19 is callee-saved so it holds RSP across the call
+	MOVD RSP, R19
 
+	// clear errno and save its address if captureErrno is set
+	MOVD ZR, ERRNO_ADDRESS(RSP)
+	MOVD syscall15Args_captureErrno(R0), R10
+	CBZ  R10, noerrno
+	MOVD ·errnoLocationABI0(SB), R10
+	CBZ  R10, noerrno
+	BL   (R10)
+	MOVW ZR, (R0)
+	MOVD R0, ERRNO_ADDRESS(RSP)
+
+noerrno:
+	MOVD PTR_ADDRESS(RSP), R9
+
+	// make room for the stack arguments keeping RSP 16 byte aligned
+	MOVD syscall15Args_numStackArgs(R9), R11
+	LSL  $3, R11, R10
+	ADD  $(NUM_STACK_REGS*8+15), R10
+	AND  $~15, R10
+	MOVD RSP, R12
+	SUB  R10, R12
+	MOVD R12, RSP
+
+	MOVD syscall15Args_a9(R9), R10
+	MOVD R10, 0(RSP)                // push a9 onto stack
+	MOVD syscall15Args_a10(R9), R10
+	MOVD R10, 8(RSP)                // push a10 onto stack
+	MOVD syscall15Args_a11(R9), R10
+	MOVD R10, 16(RSP)               // push a11 onto stack
+	MOVD syscall15Args_a12(R9), R10
+	MOVD R10, 24(RSP)               // push a12 onto stack
+	MOVD syscall15Args_a13(R9), R10
+	MOVD R10, 32(RSP)               // push a13 onto stack
+	MOVD syscall15Args_a14(R9), R10
+	MOVD R10, 40(RSP)               // push a14 onto stack
+	MOVD syscall15Args_a15(R9), R10
+	MOVD R10, 48(RSP)               // push a15 onto stack
+
+	// push the stack arguments after a15
+	MOVD syscall15Args_stackArgs(R9), R12
+	ADD  $(NUM_STACK_REGS*8), RSP, R13
+	CBZ  R11, pushed
+
+push:
+	MOVD (R12), R10
+	MOVD R10, (R13)
+	ADD  $8, R12
+	ADD  $8, R13
+	SUB  $1, R11
+	CBNZ R11, push
+
+pushed:
 	FMOVD syscall15Args_f1(R9), F0 // f1
 	FMOVD syscall15Args_f2(R9), F1 // f2
 	FMOVD syscall15Args_f3(R9), F2 // f3
@@ -61,26 +121,14 @@ TEXT syscall15X(SB), NOSPLIT, $0
 	MOVD syscall15Args_a8(R9), R7       // a8
 	MOVD syscall15Args_arm64_r8(R9), R8 // r8
 
-	MOVD syscall15Args_a9(R9), R10
-	MOVD R10, 0(RSP)                // push a9 onto stack
-	MOVD syscall15Args_a10(R9), R10
-	MOVD R10, 8(RSP)                // push a10 onto stack
-	MOVD syscall15Args_a11(R9), R10
-	MOVD R10, 16(RSP)               // push a11 onto stack
-	MOVD syscall15Args_a12(R9), R10
-	MOVD R10, 24(RSP)               // push a12 onto stack
-	MOVD syscall15Args_a13(R9), R10
-	MOVD R10, 32(RSP)               // push a13 onto stack
-	MOVD syscall15Args_a14(R9), R10
-	MOVD R10, 40(RSP)               // push a14 onto stack
-	MOVD syscall15Args_a15(R9), R10
-	MOVD R10, 48(RSP)               // push a15 onto stack
-
 	MOVD syscall15Args_fn(R9), R10 // fn
 	BL   (R10)
 
-	MOVD PTR_ADDRESS(RSP), R2 // pop structure pointer
-	ADD  $STACK_SIZE, RSP
+	MOVD R19, RSP               // pop the stack arguments
+	MOVD PTR_ADDRESS(RSP), R2   // pop structure pointer
+	MOVD ERRNO_ADDRESS(RSP), R3
+	MOVD R19_ADDRESS(RSP), R19
+	ADD  $LOCALS_SIZE, RSP
 
 	MOVD  R0, syscall15Args_a1(R2) // save r1
 	MOVD  R1, syscall15Args_a2(R2) // save r3
@@ -89,4 +137,10 @@ TEXT syscall15X(SB), NOSPLIT, $0
 	FMOVD F2, syscall15Args_f3(R2) // save f2
 	FMOVD F3, syscall15Args_f4(R2) // save f3
 
+	// read errno right after the call
+	CBZ   R3, done
+	MOVW  (R3), R3
+	MOVD  R3, syscall15Args_err(R2)
+
+done:
 	RET
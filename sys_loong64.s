@@ -7,8 +7,11 @@
 #include "go_asm.h"
 #include "funcdata.h"
 
-#define STACK_SIZE 64
-#define PTR_ADDRESS (STACK_SIZE - 8)
+#define LOCALS_SIZE 32
+#define PTR_ADDRESS (LOCALS_SIZE - 8)
+#define ERRNO_ADDRESS (LOCALS_SIZE - 16)
+#define R23_ADDRESS (LOCALS_SIZE - 24)
+#define NUM_STACK_REGS 7 // a9-a15
 
 // syscall15X calls a function in libc on behalf of the syscall package.
 // syscall15X takes a pointer to a struct like:
@@ -29,37 +32,46 @@
 //	a13    uintptr
 //	a14    uintptr
 //	a15    uintptr
-//	r1    uintptr
-//	r2    uintptr
-//	err   uintptr
+//	f1-f8    uintptr
+//	arm64_r8    uintptr
+//	err    uintptr
+//	stackArgs    uintptr
+//	numStackArgs    uintptr
+//	amd64_x87    uintptr
+//	captureErrno    uintptr
 // }
+// a9-a15 are pushed onto the stack followed by the numStackArgs words at stackArgs.
 // syscall15X must be called on the g0 stack with the
 // C calling convention (use libcCall).
 GLOBL ·syscall15XABI0(SB), NOPTR|RODATA, $8
 DATA ·syscall15XABI0(SB)/8, $syscall15X(SB)
 TEXT syscall15X(SB), NOSPLIT, $0
 	// push structure pointer
-	SUBV	$STACK_SIZE, R3
+	SUBV	$LOCALS_SIZE, R3
 	MOVV	R4, PTR_ADDRESS(R3)
-	MOVV	R4, R13
+	MOVV	R23, R23_ADDRESS(R3)	// R23 is callee-saved so it holds R3 across the call
+	MOVV	R3, R23
 
-	MOVD	syscall15Args_f1(R13), F0	// f1
-	MOVD	syscall15Args_f2(R13), F1	// f2
-	MOVD	syscall15Args_f3(R13), F2	// f3
-	MOVD	syscall15Args_f4(R13), F3	// f4
-	MOVD	syscall15Args_f5(R13), F4	// f5
-	MOVD	syscall15Args_f6(R13), F5	// f6
-	MOVD	syscall15Args_f7(R13), F6	// f7
-	MOVD	syscall15Args_f8(R13), F7	// f8
+	// clear errno and save its address if captureErrno is set
+	MOVV	R0, ERRNO_ADDRESS(R3)
+	MOVV	syscall15Args_captureErrno(R4), R12
+	BEQ	R12, noerrno
+	MOVV	·errnoLocationABI0(SB), R12
+	BEQ	R12, noerrno
+	JAL	(R12)
+	MOVW	R0, (R4)
+	MOVV	R4, ERRNO_ADDRESS(R3)
 
-	MOVV	syscall15Args_a1(R13), R4	// a1
-	MOVV	syscall15Args_a2(R13), R5	// a2
-	MOVV	syscall15Args_a3(R13), R6	// a3
-	MOVV	syscall15Args_a4(R13), R7	// a4
-	MOVV	syscall15Args_a5(R13), R8	// a5
-	MOVV	syscall15Args_a6(R13), R9	// a6
-	MOVV	syscall15Args_a7(R13), R10	// a7
-	MOVV	syscall15Args_a8(R13), R11	// a8
+noerrno:
+	MOVV	PTR_ADDRESS(R3), R13
+
+	// make room for the stack arguments keeping R3 16 byte aligned
+	MOVV	syscall15Args_numStackArgs(R13), R14
+	SLLV	$3, R14, R12
+	ADDV	$(NUM_STACK_REGS*8+15), R12
+	SRLV	$4, R12
+	SLLV	$4, R12
+	SUBV	R12, R3
 
 	// push a9-a15 onto stack
 	MOVV	syscall15Args_a9(R13), R12
@@ -77,12 +89,48 @@ TEXT syscall15X(SB), NOSPLIT, $0
 	MOVV	syscall15Args_a15(R13), R12
 	MOVV	R12, 48(R3)
 
+	// push the stack arguments after a15
+	MOVV	syscall15Args_stackArgs(R13), R15
+	ADDV	$(NUM_STACK_REGS*8), R3, R16
+	BEQ	R14, pushed
+
+push:
+	MOVV	(R15), R12
+	MOVV	R12, (R16)
+	ADDV	$8, R15
+	ADDV	$8, R16
+	SUBV	$1, R14
+	BNE	R14, push
+
+pushed:
+
+	MOVD	syscall15Args_f1(R13), F0	// f1
+	MOVD	syscall15Args_f2(R13), F1	// f2
+	MOVD	syscall15Args_f3(R13), F2	// f3
+	MOVD	syscall15Args_f4(R13), F3	// f4
+	MOVD	syscall15Args_f5(R13), F4	// f5
+	MOVD	syscall15Args_f6(R13), F5	// f6
+	MOVD	syscall15Args_f7(R13), F6	// f7
+	MOVD	syscall15Args_f8(R13), F7	// f8
+
+	MOVV	syscall15Args_a1(R13), R4	// a1
+	MOVV	syscall15Args_a2(R13), R5	// a2
+	MOVV	syscall15Args_a3(R13), R6	// a3
+	MOVV	syscall15Args_a4(R13), R7	// a4
+	MOVV	syscall15Args_a5(R13), R8	// a5
+	MOVV	syscall15Args_a6(R13), R9	// a6
+	MOVV	syscall15Args_a7(R13), R10	// a7
+	MOVV	syscall15Args_a8(R13), R11	// a8
+
 	MOVV	syscall15Args_fn(R13), R12
 	JAL	(R12)
 
-	// pop structure pointer
+	// pop the stack arguments and structure pointer
+	MOVV	R23, R3
 	MOVV	PTR_ADDRESS(R3), R13
-	ADDV	$STACK_SIZE, R3
+	MOVV	ERRNO_ADDRESS(R3), R14
+	MOVV	R23_ADDRESS(R3), R23
+	ADDV	$LOCALS_SIZE, R3
 
 	// save R4, R5
 	MOVV	R4, syscall15Args_a1(R13)
@@ -93,4 +141,11 @@ TEXT syscall15X(SB), NOSPLIT, $0
 	MOVD	F1, syscall15Args_f2(R13)
 	MOVD	F2, syscall15Args_f3(R13)
 	MOVD	F3, syscall15Args_f4(R13)
+
+	// read errno right after the call
+	BEQ	R14, done
+	MOVW	(R14), R14
+	MOVV	R14, syscall15Args_err(R13)
+
+done:
 	RET
@@ -27,7 +27,9 @@ TEXT callbackasm1(SB), NOSPLIT|NOFRAME, $0
 	STP   (R6, R7), (14*8)(R14)
 
 	// Adjust SP by frame size.
-	SUB $(26*8), RSP
+	// The frame holds R27 and R30 at 0(RSP) and the struct callbackArgs at 16(RSP)
+	// which may be up to 10 words large so that it ends right below the saved registers.
+	SUB $(28*8), RSP
 
 	// It is important to save R27 because the go assembler
 	// uses it for move instructions for a variable.
@@ -44,10 +46,12 @@ TEXT callbackasm1(SB), NOSPLIT|NOFRAME, $0
 	STP (R27, R30), 0(RSP)
 
 	// Create a struct callbackArgs on our stack.
-	MOVD $(callbackArgs__size)(RSP), R13
-	MOVD R12, callbackArgs_index(R13)    // callback index
-	MOVD R14, callbackArgs_args(R13)     // address of args vector
-	MOVD ZR, callbackArgs_result(R13)    // result
+	MOVD $16(RSP), R13
+	MOVD R12, callbackArgs_index(R13)      // callback index
+	MOVD R14, callbackArgs_args(R13)       // address of args vector
+	MOVD ZR, callbackArgs_result(R13)      // result
+	MOVD ZR, callbackArgs_floatResult(R13) // floating-point result
+	MOVD R8, callbackArgs_arm64_r8(R13)    // address of a struct result returned in memory
 
 	// Move parameters into registers
 	// Get the ABIInternal function pointer
@@ -60,11 +64,16 @@ TEXT callbackasm1(SB), NOSPLIT|NOFRAME, $0
 	BL crosscall2(SB)
 
 	// Get callback result.
-	MOVD $(callbackArgs__size)(RSP), R13
-	MOVD callbackArgs_result(R13), R0
+	MOVD $16(RSP), R13
+	MOVD  callbackArgs_result(R13), R0
+	MOVD  callbackArgs_result2(R13), R1
+	FMOVD callbackArgs_floatResult(R13), F0
+	FMOVD (callbackArgs_floatResult+8)(R13), F1
+	FMOVD (callbackArgs_floatResult+16)(R13), F2
+	FMOVD (callbackArgs_floatResult+24)(R13), F3
 
 	// Restore LR and R27
 	LDP 0(RSP), (R27, R30)
-	ADD $(26*8), RSP
+	ADD $(28*8), RSP
 
 	RET
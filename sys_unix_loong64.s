@@ -30,7 +30,10 @@ TEXT callbackasm1(SB), NOSPLIT|NOFRAME, $0
 	MOVV	R11, 120(R14)
 
 	// Adjust SP by frame size.
-	SUBV	$(22*8), R3
+	// The frame holds R1 and R30 at 0(R3) and the struct callbackArgs at 16(R3)
+	// which may be up to 9 words large so that it ends right below the saved registers.
+	// A larger frame exceeds the nosplit stack limit of callbackasm.
+	SUBV	$(27*8), R3
 
 	// It is important to save R30 because the go assembler
 	// uses it for move instructions for a variable.
@@ -48,10 +51,11 @@ TEXT callbackasm1(SB), NOSPLIT|NOFRAME, $0
 	MOVV	R30, 8(R3)
 
 	// Create a struct callbackArgs on our stack.
-	MOVV	$(callbackArgs__size)(R3), R13
-	MOVV	R12, callbackArgs_index(R13)    // callback index
-	MOVV	R14, callbackArgs_args(R13)     // address of args vector
-	MOVV	$0, callbackArgs_result(R13)    // result
+	MOVV	$16(R3), R13
+	MOVV	R12, callbackArgs_index(R13)      // callback index
+	MOVV	R14, callbackArgs_args(R13)       // address of args vector
+	MOVV	$0, callbackArgs_result(R13)      // result
+	MOVV	$0, callbackArgs_floatResult(R13) // floating-point result
 
 	// Move parameters into registers
 	// Get the ABIInternal function pointer
@@ -64,12 +68,13 @@ TEXT callbackasm1(SB), NOSPLIT|NOFRAME, $0
 	JAL	crosscall2(SB)
 
 	// Get callback result.
-	MOVV	$(callbackArgs__size)(R3), R13
+	MOVV	$16(R3), R13
 	MOVV	callbackArgs_result(R13), R4
+	MOVD	callbackArgs_floatResult(R13), F0
 
 	// Restore LR and R30
 	MOVV	0(R3), R1
 	MOVV	8(R3), R30
-	ADDV	$(22*8), R3
+	ADDV	$(27*8), R3
 
 	RET
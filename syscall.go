@@ -12,6 +12,16 @@ package purego
 // [MSDocs]: https://learn.microsoft.com/en-us/cpp/cpp/cdecl?view=msvc-170
 type CDecl struct{}
 
+// CVarArg marks the variadic parameter of a function passed to RegisterFunc as the variadic
+// arguments of a C variadic function such as printf or open. It must be the element type of the
+// last parameter of the function. The parameters before it are the fixed parameters of the C function.
+//
+//	var snprintf func(buf []byte, size uintptr, format string, args ...purego.CVarArg) int32
+//
+// The variadic arguments follow the same type conversions as fixed arguments after the C default
+// argument promotions have been applied, so a float32 is passed as a double. A nil value is passed as NULL.
+type CVarArg interface{}
+
 const (
 	maxArgs             = 15
 	numOfFloatRegisters = 8 // arm64 and amd64 both have 8 float registers
@@ -21,18 +31,32 @@ type syscall15Args struct {
 	fn, a1, a2, a3, a4, a5, a6, a7, a8, a9, a10, a11, a12, a13, a14, a15 uintptr
 	f1, f2, f3, f4, f5, f6, f7, f8                                       uintptr
 	arm64_r8                                                             uintptr
+	err                                                                  uintptr // errno after the call
+	stackArgs, numStackArgs                                              uintptr // stack arguments after a15
+	amd64_x87                                                            uintptr // nonzero if the result is a long double in ST(0) that is stored in f1 and f2
+	captureErrno                                                         uintptr // nonzero if errno is cleared before the call and stored in err after it
 }
 
+// errnoLocationABI0 is the address of the libc function that returns a pointer to errno
+// of the calling thread (__errno_location, __error or __errno). When captureErrno is set syscall15X
+// uses it to clear errno before calling the C function and to read it back right after the call on the same thread.
+// It is zero on platforms where errno can't be captured which then always report zero.
+var errnoLocationABI0 uintptr
+
 // SyscallN takes fn, a C function pointer and a list of arguments as uintptr.
-// There is an internal maximum number of arguments that SyscallN can take. It panics
-// when the maximum is exceeded. It returns the result and the libc error code if there is one.
+// On Windows and on platforms that call C through Cgo there is an internal maximum of 15 arguments
+// that SyscallN can take. It panics when the maximum is exceeded. Elsewhere the arguments that
+// don't fit into registers are all passed on the stack. It returns the result and the libc error code if there is one.
+// On Unix, the error code is the value of errno read on the same thread right after the call.
+// errno is cleared before the call so that a non-zero value was always set by fn. As in C,
+// it is only meaningful if the result of fn reports a failure.
 //
 // In order to call this function properly make sure to follow all the rules specified in [unsafe.Pointer]
 // especially point 4.
 //
 // NOTE: SyscallN does not properly call functions that have both integer and float parameters.
 // See discussion comment https://github.com/ebiten/purego/pull/1#issuecomment-1128057607
-// for an explanation of why that is.
+// for an explanation of why that is. Use SyscallNF for those functions on Unix.
 //
 // On amd64, if there are more than 8 floats the 9th and so on will be placed incorrectly on the
 // stack.
@@ -47,7 +71,8 @@ func SyscallN(fn uintptr, args ...uintptr) (r1, r2, err uintptr) {
 		panic("purego: fn is nil")
 	}
 	if len(args) > maxArgs {
-		panic("purego: too many arguments to SyscallN")
+		// panics on platforms that are limited to maxArgs
+		return syscall_syscallN(fn, args)
 	}
 	// add padding so there is no out-of-bounds slicing
 	var tmp [maxArgs]uintptr
@@ -16,6 +16,22 @@ func syscall_syscall15X(fn, a1, a2, a3, a4, a5, a6, a7, a8, a9, a10, a11, a12, a
 	return cgo.Syscall15X(fn, a1, a2, a3, a4, a5, a6, a7, a8, a9, a10, a11, a12, a13, a14, a15)
 }
 
+func syscall_syscallN(fn uintptr, args []uintptr) (r1, r2, err uintptr) {
+	panic("purego: too many arguments to SyscallN")
+}
+
 func NewCallback(_ any) uintptr {
 	panic("purego: NewCallback on Linux is only supported on amd64/arm64/loong64")
 }
+
+func NewReleasableCallback(_ any) *Callback {
+	panic("purego: NewReleasableCallback on Linux is only supported on amd64/arm64/loong64")
+}
+
+func CallbackSlots() (used, remaining int) {
+	panic("purego: CallbackSlots on Linux is only supported on amd64/arm64/loong64")
+}
+
+func releaseCallback(index int) {
+	panic("purego: releaseCallback on Linux is only supported on amd64/arm64/loong64")
+}
@@ -6,35 +6,147 @@
 package purego
 
 import (
+	"math"
 	"reflect"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"unsafe"
+
+	"github.com/ebitengine/purego/internal/strings"
 )
 
 var syscall15XABI0 uintptr
 
 func syscall_syscall15X(fn, a1, a2, a3, a4, a5, a6, a7, a8, a9, a10, a11, a12, a13, a14, a15 uintptr) (r1, r2, err uintptr) {
 	args := thePool.Get().(*syscall15Args)
-	defer thePool.Put(args)
+	defer putSyscall(args)
 
 	*args = syscall15Args{
 		fn, a1, a2, a3, a4, a5, a6, a7, a8, a9, a10, a11, a12, a13, a14, a15,
 		a1, a2, a3, a4, a5, a6, a7, a8,
-		0,
+		0, 0, 0, 0, 0, 1,
+	}
+
+	runtime_cgocall(syscall15XABI0, unsafe.Pointer(args))
+	return args.a1, args.a2, args.err
+}
+
+// syscall_syscallN is like syscall_syscall15X but takes more than maxArgs arguments.
+// The arguments after the first 15 are passed on the stack after a15.
+func syscall_syscallN(fn uintptr, a []uintptr) (r1, r2, err uintptr) {
+	args := thePool.Get().(*syscall15Args)
+	defer putSyscall(args)
+
+	stack := a[maxArgs:]
+	*args = syscall15Args{
+		fn, a[0], a[1], a[2], a[3], a[4], a[5], a[6], a[7], a[8], a[9], a[10], a[11], a[12], a[13], a[14],
+		a[0], a[1], a[2], a[3], a[4], a[5], a[6], a[7],
+		0, 0, uintptr(unsafe.Pointer(&stack[0])), uintptr(len(stack)), 0, 1,
+	}
+
+	runtime_cgocall(syscall15XABI0, unsafe.Pointer(args))
+	runtime.KeepAlive(stack)
+	return args.a1, args.a2, args.err
+}
+
+// SyscallNF is like SyscallN but takes the integer and floating-point arguments of fn as separate lists.
+// This makes it possible to call C functions that mix both, such as void draw(int x, float alpha, int y),
+// without reflection. ints are passed in order in the integer registers and floats in order in the
+// floating-point registers, independently of each other. The integer arguments that don't fit into
+// registers are passed on the stack. At most 8 floating-point arguments can be passed.
+//
+// It returns the integer result, the floating-point result and the libc error code like SyscallN.
+// Each float64 is passed as a C double and the floating-point result is read as a C double. For a C float,
+// the value is in the lower 32 bits:
+//
+//	alpha := math.Float64frombits(uint64(math.Float32bits(0.5)))
+//	_, f, _ := purego.SyscallNF(draw, []uintptr{x, y}, []float64{alpha})
+//	result := math.Float32frombits(uint32(math.Float64bits(f)))
+//
+// The same rules as for SyscallN apply to integer arguments that are pointers.
+func SyscallNF(fn uintptr, ints []uintptr, floats []float64) (r1 uintptr, f1 float64, err uintptr) {
+	if fn == 0 {
+		panic("purego: fn is nil")
+	}
+	if len(floats) > numOfFloatRegisters {
+		panic("purego: too many float arguments to SyscallNF")
+	}
+	const is32bit = unsafe.Sizeof(uintptr(0)) == 4
+	if is32bit && len(floats) > 0 {
+		panic("purego: floats only supported on 64bit platforms")
+	}
+	var a [maxArgs]uintptr
+	var stack []uintptr
+	if n := copy(a[:], ints); n < len(ints) {
+		if !stackArgsUnlimited() {
+			panic("purego: too many arguments to SyscallNF")
+		}
+		stack = ints[n:]
+	}
+	var f [numOfFloatRegisters]uintptr
+	for i, x := range floats {
+		f[i] = uintptr(math.Float64bits(x))
+	}
+
+	args := thePool.Get().(*syscall15Args)
+	defer putSyscall(args)
+
+	var stackPtr uintptr
+	if len(stack) > 0 {
+		stackPtr = uintptr(unsafe.Pointer(&stack[0]))
+	}
+	*args = syscall15Args{
+		fn, a[0], a[1], a[2], a[3], a[4], a[5], a[6], a[7], a[8], a[9], a[10], a[11], a[12], a[13], a[14],
+		f[0], f[1], f[2], f[3], f[4], f[5], f[6], f[7],
+		0, 0, stackPtr, uintptr(len(stack)), 0, 1,
 	}
 
 	runtime_cgocall(syscall15XABI0, unsafe.Pointer(args))
-	return args.a1, args.a2, 0
+	runtime.KeepAlive(ints)
+	return args.a1, math.Float64frombits(uint64(args.f1)), args.err
 }
 
 // NewCallback converts a Go function to a function pointer conforming to the C calling convention.
 // This is useful when interoperating with C code requiring callbacks. The argument is expected to be a
-// function with zero or one uintptr-sized result. The function must not have arguments with size larger than the size
-// of uintptr. Only a limited number of callbacks may be created in a single Go process, and any memory allocated
-// for these callbacks is never released. At least 2000 callbacks can always be created. Although this function
-// provides similar functionality to windows.NewCallback it is distinct.
+// function with zero or one uintptr-sized or floating-point result. The function must not have arguments with size
+// larger than the size of uintptr. Only a limited number of callbacks may be created in a single Go process, and any memory allocated
+// for these callbacks is never released. Use NewReleasableCallback for callbacks that are only needed for a while.
+// At least 2000 callbacks can always be created. On Linux and FreeBSD amd64 and arm64 more are created in
+// executable memory mapped on demand. Although this function provides similar functionality to
+// windows.NewCallback it is distinct.
+//
+// A string argument is copied from a NUL-terminated char* and a func argument calls the C function pointer
+// it receives like a function registered with RegisterFunc. A NULL function pointer is a nil func.
+//
+// On darwin and linux amd64 and arm64 structs may be passed and returned by value like with RegisterFunc,
+// except for structs with string or func fields.
+//
+// The result may be a CMarshaler. An argument may be of a type that is both a CMarshaler and a CUnmarshaler.
+// It then takes as many integer and floating-point values as its MarshalC appends.
 func NewCallback(fn any) uintptr {
+	return newCallback(fn).ptr
+}
+
+// NewReleasableCallback is like NewCallback but returns a Callback whose slot can be reused
+// by another callback after it is released.
+func NewReleasableCallback(fn any) *Callback {
+	return newCallback(fn)
+}
+
+// CallbackSlots returns the number of callbacks that are in use and the number that can still be created.
+// remaining is -1 if more callbacks are created on demand until the process runs out of memory.
+func CallbackSlots() (used, remaining int) {
+	cbs.lock.Lock()
+	defer cbs.lock.Unlock()
+	used = cbs.num - len(cbs.free)
+	if callbacksGrow {
+		return used, -1
+	}
+	return used, maxCB - used
+}
+
+func newCallback(fn any) *Callback {
 	ty := reflect.TypeOf(fn)
 	for i := 0; i < ty.NumIn(); i++ {
 		in := ty.In(i)
@@ -48,14 +160,64 @@ func NewCallback(fn any) uintptr {
 	return compileCallback(fn)
 }
 
-// maxCb is the maximum number of callbacks
-// only increase this if you have added more to the callbackasm function
+// maxCb is the number of callbacks in the callbackasm function.
+// Only increase this if you have added more to the callbackasm function.
+// The callbacks past it use trampolines from newTrampoline if callbacksGrow is set.
 const maxCB = 2000
 
+// callbackChunkSize is the number of callbacks in a chunk of cbs.table.
+const callbackChunkSize = 1024
+
 var cbs struct {
-	lock  sync.Mutex
-	numFn int                  // the number of functions currently in cbs.funcs
-	funcs [maxCB]reflect.Value // the saved callbacks
+	lock  sync.Mutex   // serializes creating and releasing callbacks
+	num   int          // the number of callbacks including released ones
+	free  []int        // the indices of released callbacks
+	addrs []uintptr    // the addresses of the trampolines of the callbacks past maxCB
+	table atomic.Value // a []*callbackChunk which callbackWrap reads without the lock
+}
+
+// callbackChunk holds a *callbackEntry for each callback or nil for released ones.
+// The entries are accessed atomically. A chunk is never moved so that the table
+// only has to be copied when a chunk is added.
+type callbackChunk [callbackChunkSize]unsafe.Pointer
+
+// callbackEntry is the Go function of a callback.
+type callbackEntry struct {
+	fn        reflect.Value         // the function called with reflection
+	typed     func(a *callbackArgs) // the function of a typed callback which decodes its own arguments
+	unmarshal *callbackUnmarshal    // the CUnmarshaler arguments of fn or nil if there are none
+}
+
+// callbackUnmarshal describes the CUnmarshaler arguments of a callback. It is computed once by
+// compileCallback so that callbackWrap doesn't call CSize for every argument of every call.
+type callbackUnmarshal struct {
+	sizes        []cSize // the values of each argument
+	ints, floats int     // the number of values of all arguments together
+}
+
+// cSize is what CSize returns for a CUnmarshaler argument.
+type cSize struct {
+	ints, floats int
+	unmarshal    bool // the argument is a CUnmarshaler
+}
+
+// loadCallback returns the entry of the callback at index or nil if it was released.
+func loadCallback(index int) *callbackEntry {
+	chunks := cbs.table.Load().([]*callbackChunk)
+	return (*callbackEntry)(atomic.LoadPointer(&chunks[index/callbackChunkSize][index%callbackChunkSize]))
+}
+
+// storeCallback sets the entry of the callback at index. cbs.lock must be held.
+func storeCallback(index int, e *callbackEntry) {
+	chunks, _ := cbs.table.Load().([]*callbackChunk)
+	if index/callbackChunkSize == len(chunks) {
+		grown := make([]*callbackChunk, len(chunks)+1)
+		copy(grown, chunks)
+		grown[len(chunks)] = new(callbackChunk)
+		cbs.table.Store(grown)
+		chunks = grown
+	}
+	atomic.StorePointer(&chunks[index/callbackChunkSize][index%callbackChunkSize], unsafe.Pointer(e))
 }
 
 type callbackArgs struct {
@@ -72,9 +234,19 @@ type callbackArgs struct {
 	args unsafe.Pointer
 	// Below are out-args from callbackWrap
 	result uintptr
+	// result2 is loaded into the second integer result register for struct results.
+	result2 uintptr
+	// floatResult holds the bits of a float64 or in its lower 32 bits of a float32
+	// for each floating-point result register. Only struct results use more than the first.
+	floatResult [4]uintptr
+	// arm64_r8 is X8 on entry which holds the address of a struct result that is returned in memory on arm64.
+	arm64_r8 uintptr
 }
 
-func compileCallback(fn any) uintptr {
+// callbackasm1 on loong64 reserves 9 words and on arm64 10 words for callbackArgs below the saved argument registers.
+const _ = uint(9*8 - unsafe.Sizeof(callbackArgs{}))
+
+func compileCallback(fn any) *Callback {
 	val := reflect.ValueOf(fn)
 	if val.Kind() != reflect.Func {
 		panic("purego: the type must be a function but was not")
@@ -83,41 +255,97 @@ func compileCallback(fn any) uintptr {
 		panic("purego: function must not be nil")
 	}
 	ty := val.Type()
+	var unmarshal *callbackUnmarshal
 	for i := 0; i < ty.NumIn(); i++ {
 		in := ty.In(i)
+		if isCUnmarshaler(in) {
+			if !isCMarshaler(in) {
+				panic("purego: callback argument " + in.String() + " must also implement CMarshaler")
+			}
+			if unmarshal == nil {
+				unmarshal = &callbackUnmarshal{sizes: make([]cSize, ty.NumIn())}
+			}
+			numInts, numFloats := numMarshaledValues(in)
+			unmarshal.sizes[i] = cSize{ints: numInts, floats: numFloats, unmarshal: true}
+			unmarshal.ints += numInts
+			unmarshal.floats += numFloats
+			continue
+		}
 		switch in.Kind() {
 		case reflect.Struct:
 			if i == 0 && in.AssignableTo(reflect.TypeOf(CDecl{})) {
 				continue
 			}
-			fallthrough
-		case reflect.Interface, reflect.Func, reflect.Slice,
+			checkCallbackStruct(in)
+		case reflect.Func:
+			if err := Validate(reflect.New(in).Interface()); err != nil {
+				panic(err.Error())
+			}
+		case reflect.Interface, reflect.Slice,
 			reflect.Chan, reflect.Complex64, reflect.Complex128,
-			reflect.String, reflect.Map, reflect.Invalid:
+			reflect.Map, reflect.Invalid:
 			panic("purego: unsupported argument type: " + in.Kind().String())
 		}
 	}
 output:
 	switch {
 	case ty.NumOut() == 1:
+		if isCMarshaler(ty.Out(0)) {
+			break output
+		}
+		if ty.Out(0).Kind() == reflect.Struct {
+			checkCallbackStruct(ty.Out(0))
+			break output
+		}
 		switch ty.Out(0).Kind() {
 		case reflect.Pointer, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
-			reflect.Bool, reflect.UnsafePointer:
+			reflect.Bool, reflect.UnsafePointer, reflect.Float32, reflect.Float64:
 			break output
 		}
 		panic("purego: unsupported return type: " + ty.String())
 	case ty.NumOut() > 1:
 		panic("purego: callbacks can only have one return")
 	}
+	return addCallback(&callbackEntry{fn: val, unmarshal: unmarshal})
+}
+
+// addCallback stores e in a released slot or a new one and returns its callback.
+func addCallback(e *callbackEntry) *Callback {
 	cbs.lock.Lock()
 	defer cbs.lock.Unlock()
-	if cbs.numFn >= maxCB {
+	if n := len(cbs.free); n > 0 {
+		index := cbs.free[n-1]
+		cbs.free = cbs.free[:n-1]
+		storeCallback(index, e)
+		return &Callback{ptr: callbackAddr(index), index: index}
+	}
+	index := cbs.num
+	switch {
+	case index < maxCB:
+	case callbacksGrow:
+		cbs.addrs = append(cbs.addrs, newTrampoline(index))
+	default:
 		panic("purego: the maximum number of callbacks has been reached")
 	}
-	cbs.funcs[cbs.numFn] = val
-	cbs.numFn++
-	return callbackasmAddr(cbs.numFn - 1)
+	storeCallback(index, e)
+	cbs.num++
+	return &Callback{ptr: callbackAddr(index), index: index}
+}
+
+// callbackAddr returns the address that calls the callback at index. cbs.lock must be held.
+func callbackAddr(index int) uintptr {
+	if index < maxCB {
+		return callbackasmAddr(index)
+	}
+	return cbs.addrs[index-maxCB]
+}
+
+func releaseCallback(index int) {
+	cbs.lock.Lock()
+	defer cbs.lock.Unlock()
+	storeCallback(index, nil)
+	cbs.free = append(cbs.free, index)
 }
 
 const ptrSize = unsafe.Sizeof((*int)(nil))
@@ -138,47 +366,125 @@ var callbackWrap_call = callbackWrap
 
 // callbackWrap is called by assembly code which determines which Go function to call.
 // This function takes the arguments and passes them to the Go function and returns the result.
+// callbackFuncKey identifies a C function pointer passed to a callback as a func argument of type ty.
+type callbackFuncKey struct {
+	ty  reflect.Type
+	cfn uintptr
+}
+
+// callbackFuncs holds the Go functions that call the C function pointers passed to callbacks.
+var callbackFuncs struct {
+	lock  sync.Mutex
+	funcs map[callbackFuncKey]reflect.Value
+}
+
+// callbackFunc returns a Go function of type ty that calls cfn. C usually passes the same few
+// function pointers to a callback so each is only registered the first time it is seen.
+func callbackFunc(ty reflect.Type, cfn uintptr) reflect.Value {
+	key := callbackFuncKey{ty: ty, cfn: cfn}
+	callbackFuncs.lock.Lock()
+	defer callbackFuncs.lock.Unlock()
+	if fn, ok := callbackFuncs.funcs[key]; ok {
+		return fn
+	}
+	if callbackFuncs.funcs == nil {
+		callbackFuncs.funcs = make(map[callbackFuncKey]reflect.Value)
+	}
+	fn := reflect.New(ty).Elem()
+	RegisterFunc(fn.Addr().Interface(), cfn)
+	callbackFuncs.funcs[key] = fn
+	return fn
+}
+
 func callbackWrap(a *callbackArgs) {
-	cbs.lock.Lock()
-	fn := cbs.funcs[a.index]
-	cbs.lock.Unlock()
+	e := loadCallback(int(a.index))
+	if e == nil {
+		panic("purego: a released callback was called")
+	}
+	if e.typed != nil {
+		e.typed(a)
+		return
+	}
+	fn := e.fn
 	fnType := fn.Type()
 	args := make([]reflect.Value, fnType.NumIn())
-	frame := (*[callbackMaxFrame]uintptr)(a.args)
-	var floatsN int // floatsN represents the number of float arguments processed
-	var intsN int   // intsN represents the number of integer arguments processed
-	// stack points to the index into frame of the current stack element.
-	// The stack begins after the float and integer registers.
-	stack := numOfIntegerRegisters() + numOfFloatRegisters
+	f := &callbackFrame{
+		frame: (*[callbackMaxFrame]uintptr)(a.args),
+		// The stack begins after the float and integer registers.
+		stack: numOfIntegerRegisters() + numOfFloatRegisters,
+	}
+	var resultPtr unsafe.Pointer // the memory of a struct result that is returned in memory
+	if fnType.NumOut() == 1 && fnType.Out(0).Kind() == reflect.Struct && !isCMarshaler(fnType.Out(0)) {
+		if t := cStructType(fnType.Out(0)); t.Size() > 0 && structReturnedInMemory(t) {
+			resultPtr = callbackResultPointer(a, f)
+		}
+	}
+	// the values of all CUnmarshaler arguments share one slice of each kind
+	var unmarshalInts []uintptr
+	var unmarshalFloats []float64
+	if u := e.unmarshal; u != nil {
+		unmarshalInts = make([]uintptr, u.ints)
+		unmarshalFloats = make([]float64, u.floats)
+	}
 	for i := range args {
+		in := fnType.In(i)
+		if e.unmarshal != nil && e.unmarshal.sizes[i].unmarshal {
+			size := e.unmarshal.sizes[i]
+			ints := unmarshalInts[:size.ints:size.ints]
+			unmarshalInts = unmarshalInts[size.ints:]
+			for j := range ints {
+				ints[j] = f.frame[f.nextInt()]
+			}
+			floats := unmarshalFloats[:size.floats:size.floats]
+			unmarshalFloats = unmarshalFloats[size.floats:]
+			for j := range floats {
+				floats[j] = math.Float64frombits(uint64(f.frame[f.nextFloat()]))
+			}
+			args[i] = unmarshalC(in, ints, floats)
+			continue
+		}
 		var pos int
-		switch fnType.In(i).Kind() {
+		switch in.Kind() {
 		case reflect.Float32, reflect.Float64:
-			if floatsN >= numOfFloatRegisters {
-				pos = stack
-				stack++
+			pos = f.nextFloat()
+		case reflect.String:
+			args[i] = reflect.New(in).Elem()
+			args[i].SetString(strings.GoString(f.frame[f.nextInt()]))
+			continue
+		case reflect.Func:
+			if cfn := f.frame[f.nextInt()]; cfn != 0 {
+				args[i] = callbackFunc(in, cfn)
 			} else {
-				pos = floatsN
+				args[i] = reflect.Zero(in)
 			}
-			floatsN++
-		case reflect.Struct:
-			// This is the CDecl field
-			args[i] = reflect.Zero(fnType.In(i))
 			continue
-		default:
-
-			if intsN >= numOfIntegerRegisters() {
-				pos = stack
-				stack++
+		case reflect.Struct:
+			if i == 0 && in.AssignableTo(reflect.TypeOf(CDecl{})) {
+				args[i] = reflect.Zero(in)
+				continue
+			}
+			if l := structLayoutOf(in); l != nil {
+				args[i] = l.fromC(callbackStruct(l.ctype, f), in)
 			} else {
-				// the integers begin after the floats in frame
-				pos = intsN + numOfFloatRegisters
+				args[i] = callbackStruct(in, f)
 			}
-			intsN++
+			continue
+		default:
+			pos = f.nextInt()
 		}
-		args[i] = reflect.NewAt(fnType.In(i), unsafe.Pointer(&frame[pos])).Elem()
+		args[i] = reflect.NewAt(in, unsafe.Pointer(&f.frame[pos])).Elem()
 	}
 	ret := fn.Call(args)
+	if len(ret) > 0 && isCMarshaler(ret[0].Type()) {
+		ints, floats := marshalC(ret[0])
+		if len(ints) > 0 {
+			a.result = ints[0]
+		}
+		if len(floats) > 0 {
+			a.floatResult[0] = uintptr(math.Float64bits(floats[0]))
+		}
+		return
+	}
 	if len(ret) > 0 {
 		switch k := ret[0].Kind(); k {
 		case reflect.Uint, reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8, reflect.Uintptr:
@@ -195,12 +501,124 @@ func callbackWrap(a *callbackArgs) {
 			a.result = ret[0].Pointer()
 		case reflect.UnsafePointer:
 			a.result = ret[0].Pointer()
+		case reflect.Float32:
+			a.floatResult[0] = uintptr(math.Float32bits(float32(ret[0].Float())))
+		case reflect.Float64:
+			a.floatResult[0] = uintptr(math.Float64bits(ret[0].Float()))
+		case reflect.Struct:
+			v := ret[0]
+			if l := structLayoutOf(v.Type()); l != nil {
+				v = l.toC(v)
+			}
+			if resultPtr != nil {
+				reflect.NewAt(v.Type(), resultPtr).Elem().Set(v)
+				// the address of the result is returned in the first integer register
+				a.result = uintptr(resultPtr)
+			} else {
+				setCallbackStructResult(a, v)
+			}
 		default:
 			panic("purego: unsupported kind: " + k.String())
 		}
 	}
 }
 
+// callbackFrame reads the arguments of a callback from the saved registers and the stack.
+type callbackFrame struct {
+	// frame is a continuous block of memory with the float registers
+	// followed by the integer registers followed by the stack.
+	frame  *[callbackMaxFrame]uintptr
+	ints   int // the number of integer registers used
+	floats int // the number of float registers used
+	stack  int // the index into frame of the next stack element
+}
+
+// nextInt returns the index into frame of the next integer argument.
+func (f *callbackFrame) nextInt() (pos int) {
+	if f.ints >= numOfIntegerRegisters() {
+		return f.nextStack()
+	}
+	// the integers begin after the floats in frame
+	pos = f.ints + numOfFloatRegisters
+	f.ints++
+	return pos
+}
+
+// nextFloat returns the index into frame of the next floating-point argument.
+func (f *callbackFrame) nextFloat() (pos int) {
+	if f.floats >= numOfFloatRegisters {
+		return f.nextStack()
+	}
+	pos = f.floats
+	f.floats++
+	return pos
+}
+
+// nextStack returns the index into frame of the next stack element.
+func (f *callbackFrame) nextStack() (pos int) {
+	pos = f.stack
+	f.stack++
+	return pos
+}
+
+// readStack returns a value of the struct type t copied from the next stack elements.
+// A struct that is aligned to 16 bytes starts at an even stack element.
+func (f *callbackFrame) readStack(t reflect.Type) reflect.Value {
+	if cStructAlign(t) > 8 && f.stack%2 != 0 {
+		f.stack++
+	}
+	v := reflect.New(t).Elem()
+	readStruct8ByteChunks(v.Addr().UnsafePointer(), t.Size(), func() uintptr { return f.frame[f.nextStack()] })
+	return v
+}
+
+// readStruct8ByteChunks fills the memory at ptr of size bytes with 8-byte chunks returned by nextChunk.
+// It is the inverse of copyStruct8ByteChunks.
+func readStruct8ByteChunks(ptr unsafe.Pointer, size uintptr, nextChunk func() uintptr) {
+	for offset := uintptr(0); offset < size; offset += 8 {
+		chunk := nextChunk()
+		if remaining := size - offset; remaining >= 8 {
+			*(*uintptr)(unsafe.Add(ptr, offset)) = chunk
+		} else {
+			for i := uintptr(0); i < remaining; i++ {
+				*(*byte)(unsafe.Add(ptr, offset+i)) = byte(chunk >> (i * 8))
+			}
+		}
+	}
+}
+
+// checkCallbackStruct panics if the struct t can't be an argument or the result of a callback.
+func checkCallbackStruct(t reflect.Type) {
+	if !structsSupported() {
+		panic("purego: struct arguments and results of callbacks are only supported on darwin and linux amd64/arm64")
+	}
+	checkStructFieldsSupported(t)
+	if hasCLayoutPointers(t) {
+		panic("purego: struct " + t.String() + " with string or func fields is not supported in callbacks")
+	}
+	if containsLongDouble(cStructType(t)) {
+		panic("purego: long double is not supported in callbacks")
+	}
+}
+
+// hasCLayoutPointers reports whether t has string or func fields which are converted to C pointers.
+func hasCLayoutPointers(t reflect.Type) bool {
+	for t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Func:
+		return true
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if hasCLayoutPointers(t.Field(i).Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // callbackasmAddr returns address of runtime.callbackasm
 // function adjusted by i.
 // On x86 and amd64, runtime.callbackasm is a series of CALL instructions,
@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build darwin || freebsd || (linux && (amd64 || arm64 || loong64)) || netbsd
+
+package purego_test
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/ebitengine/purego"
+	"github.com/ebitengine/purego/internal/load"
+)
+
+func TestSyscallNF(t *testing.T) {
+	libFileName := filepath.Join(t.TempDir(), "abitest.so")
+	if err := buildSharedLib("CC", libFileName, filepath.Join("testdata", "abitest", "abi_test.c")); err != nil {
+		t.Fatal(err)
+	}
+	lib, err := load.OpenLibrary(libFileName)
+	if err != nil {
+		t.Fatalf("Failed to open library %q: %v", libFileName, err)
+	}
+	t.Cleanup(func() {
+		if err := load.CloseLibrary(lib); err != nil {
+			t.Errorf("Failed to close library: %v", err)
+		}
+	})
+
+	mixed, err := load.OpenSymbol(lib, "mixed_int_float_double")
+	if err != nil {
+		t.Fatalf("Failed to find mixed_int_float_double: %v", err)
+	}
+	alpha := math.Float64frombits(uint64(math.Float32bits(0.5)))
+	if r1, _, _ := purego.SyscallNF(mixed, []uintptr{1, 2}, []float64{alpha, 3}); int64(r1) != 3206 {
+		t.Errorf("mixed_int_float_double failed. got %d but wanted %d", int64(r1), 3206)
+	}
+
+	benchMixed, err := load.OpenSymbol(lib, "bench_mixed")
+	if err != nil {
+		t.Fatalf("Failed to find bench_mixed: %v", err)
+	}
+	if _, f1, _ := purego.SyscallNF(benchMixed, []uintptr{1, 3}, []float64{2.5, 4.5}); f1 != 11 {
+		t.Errorf("bench_mixed failed. got %f but wanted %f", f1, 11.0)
+	}
+}
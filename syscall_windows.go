@@ -15,6 +15,10 @@ func syscall_syscall15X(fn, a1, a2, a3, a4, a5, a6, a7, a8, a9, a10, a11, a12, a
 	return r1, r2, uintptr(errno)
 }
 
+func syscall_syscallN(fn uintptr, args []uintptr) (r1, r2, err uintptr) {
+	panic("purego: too many arguments to SyscallN")
+}
+
 // NewCallback converts a Go function to a function pointer conforming to the stdcall calling convention.
 // This is useful when interoperating with Windows code requiring callbacks. The argument is expected to be a
 // function with one uintptr-sized result. The function must not have arguments with size larger than the
@@ -41,6 +45,20 @@ func NewCallback(fn any) uintptr {
 	return syscall.NewCallback(fn)
 }
 
+// NewReleasableCallback panics on Windows because the callbacks of the syscall package are never released.
+func NewReleasableCallback(fn any) *Callback {
+	panic("purego: NewReleasableCallback is not supported on Windows")
+}
+
+// CallbackSlots panics on Windows because the callbacks are managed by the syscall package.
+func CallbackSlots() (used, remaining int) {
+	panic("purego: CallbackSlots is not supported on Windows")
+}
+
+func releaseCallback(index int) {
+	panic("purego: releaseCallback should not be called on Windows")
+}
+
 func loadSymbol(handle uintptr, name string) (uintptr, error) {
 	return syscall.GetProcAddress(syscall.Handle(handle), name)
 }
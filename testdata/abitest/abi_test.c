@@ -130,6 +130,15 @@ void stack_25_int64_exceeds(char *buf, size_t bufsize, int64_t a1, int64_t a2, i
              a1, a2, a3, a4, a5, a6, a7, a8, a9, a10, a11, a12, a13, a14, a15, a16, a17, a18, a19, a20, a21, a22, a23, a24, a25);
 }
 
+void stack_10_int64_10_double(char *buf, size_t bufsize, int64_t a1, double f1, int64_t a2, double f2, int64_t a3, double f3, int64_t a4, double f4, int64_t a5, double f5, int64_t a6, double f6, int64_t a7, double f7, int64_t a8, double f8, int64_t a9, double f9, int64_t a10, double f10) {
+    snprintf(buf, bufsize, "%" PRId64 ":%.1f:%" PRId64 ":%.1f:%" PRId64 ":%.1f:%" PRId64 ":%.1f:%" PRId64 ":%.1f:%" PRId64 ":%.1f:%" PRId64 ":%.1f:%" PRId64 ":%.1f:%" PRId64 ":%.1f:%" PRId64 ":%.1f",
+             a1, f1, a2, f2, a3, f3, a4, f4, a5, f5, a6, f6, a7, f7, a8, f8, a9, f9, a10, f10);
+}
+
+int64_t mixed_int_float_double(int32_t x, float alpha, int32_t y, double z) {
+    return x + (int64_t)(alpha * 10) + y * 100 + (int64_t)z * 1000;
+}
+
 // Benchmark functions - minimal work to measure call overhead
 int64_t bench_noop(void) {
     return 0;
@@ -162,3 +171,77 @@ double bench_3float(double a, double b, double c) {
 double bench_mixed(int64_t a, double b, int64_t c, double d) {
     return (double)a + b + (double)c + d;
 }
+
+int64_t bench_string(const char *s, int64_t n) {
+    return s[0] + n;
+}
+
+// bench_callback calls cb n times like qsort calls its comparator and returns the sum of the results.
+int64_t bench_callback(int64_t (*cb)(int64_t, int64_t), int64_t n) {
+    int64_t sum = 0;
+    for (int64_t i = 0; i < n; i++) {
+        sum += cb(i, 1);
+    }
+    return sum;
+}
+
+// join_strings writes the NULL-terminated array of strings separated by commas into buf
+// and returns the number of strings.
+int32_t join_strings(char *buf, const char **strs) {
+    int32_t n = 0;
+    buf[0] = '\0';
+    for (; strs[n] != NULL; n++) {
+        if (n > 0) {
+            strcat(buf, ",");
+        }
+        strcat(buf, strs[n]);
+    }
+    return n;
+}
+
+// replace_string replaces the string that s points to if it is "old".
+void replace_string(const char **s) {
+    if (strcmp(*s, "old") == 0) {
+        *s = "new";
+    }
+}
+
+// add_count adds n to the count that count points to.
+void add_count(int32_t *count, int32_t n) {
+    *count += n;
+}
+
+struct dims {
+    int8_t kind;
+    int32_t width;
+    const char *name;
+};
+
+// get_dims fills d and returns 0 or -1 if d is NULL.
+int32_t get_dims(struct dims *d) {
+    if (d == NULL) {
+        return -1;
+    }
+    d->kind++;
+    d->width = 640;
+    d->name = "screen";
+    return 0;
+}
+
+struct duration {
+    int64_t sec;
+    int64_t nsec;
+};
+
+int64_t duration_ms(struct duration d) {
+    return d.sec * 1000 + d.nsec / 1000000;
+}
+
+struct duration ms_duration(int64_t ms) {
+    struct duration d = {ms / 1000, (ms % 1000) * 1000000};
+    return d;
+}
+
+int64_t call_with_duration(int64_t (*f)(struct duration, double), int64_t ms, double scale) {
+    return f(ms_duration(ms), scale);
+}
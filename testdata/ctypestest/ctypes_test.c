@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+#include <complex.h>
+#include <stdint.h>
+
+float complex ComplexFloatMul(float complex a, float complex b) {
+    return a * b;
+}
+
+double complex ComplexDoubleAdd(double complex a, double complex b, double c) {
+    return a + b + c;
+}
+
+double ComplexDoubleParts(double complex a) {
+    return creal(a) - cimag(a);
+}
+
+struct FloatComplex {
+    float complex c;
+    float x;
+};
+
+float FloatComplexSum(struct FloatComplex s) {
+    return crealf(s.c) + cimagf(s.c) + s.x;
+}
+
+__int128 Int128Add(__int128 a, __int128 b) {
+    return a + b;
+}
+
+// Int128AfterFiveInts checks that an __int128 starts at an even register on arm64
+// and is passed on the stack on amd64 when only one integer register is left.
+__int128 Int128AfterFiveInts(int64_t a, int64_t b, int64_t c, int64_t d, int64_t e, __int128 x) {
+    return x + a + b + c + d + e;
+}
+
+unsigned __int128 Uint128Shift(unsigned __int128 a, int32_t n) {
+    return a << n;
+}
+
+#if defined(__x86_64__)
+long double LongDoubleScale(long double a, int32_t n) {
+    return a * n;
+}
+
+// LongDoubleAfterInt checks that a long double is aligned to 16 bytes on the stack.
+int64_t LongDoubleAfterInt(int64_t a, int64_t b, int64_t c, int64_t d, int64_t e, int64_t f, int64_t g, long double x) {
+    return a + b + c + d + e + f + g + (int64_t)x;
+}
+
+// LongDoublePrecise returns a value that can't be represented by a double.
+long double LongDoublePrecise(void) {
+    return 1.0L + 0x1p-60L;
+}
+
+double DoubleAbs(double x) {
+    return x < 0 ? -x : x;
+}
+#endif
+
+#include <limits.h>
+#include <stddef.h>
+#include <wchar.h>
+
+// CTypeSize returns the size of the C type that is numbered like in TestCScalarTypes.
+size_t CTypeSize(int which) {
+    switch (which) {
+    case 0: return sizeof(char);
+    case 1: return sizeof(int);
+    case 2: return sizeof(long);
+    case 3: return sizeof(size_t);
+    case 4: return sizeof(wchar_t);
+    }
+    return 0;
+}
+
+// CTypeSigned reports whether the C type that is numbered like in CTypeSize is signed.
+int CTypeSigned(int which) {
+    switch (which) {
+    case 0: return (char)-1 < 0;
+    case 1: return (int)-1 < 0;
+    case 2: return (long)-1 < 0;
+    case 3: return (size_t)-1 < 0;
+    case 4: return (wchar_t)-1 < 0;
+    }
+    return -1;
+}
+
+long LongMin(void) {
+    return LONG_MIN;
+}
+
+unsigned long ULongMax(void) {
+    return ULONG_MAX;
+}
+
+char CharMax(void) {
+    return CHAR_MAX;
+}
+
+long ApplyLong(long (*f)(long, char), long x, char c) {
+    return f(x, c);
+}
+
+struct Text {
+    char c;
+    long l;
+    wchar_t w;
+};
+
+long TextSum(struct Text t) {
+    return t.c + t.l + t.w;
+}
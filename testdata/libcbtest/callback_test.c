@@ -11,3 +11,109 @@ int callCallback(const void *fp, const char *s) {
     ((callback)(fp))(s, strlen(s));
     return sentinel;
 }
+
+typedef double (*easing)(double);
+
+double sumEasing(const void *fp, int steps) {
+    double sum = 0;
+    for (int i = 0; i <= steps; i++) {
+        sum += ((easing)(fp))((double)i / steps);
+    }
+    return sum;
+}
+
+typedef float (*sample)(int);
+
+float sumSamples(const void *fp, int n) {
+    float sum = 0;
+    for (int i = 0; i < n; i++) {
+        sum += ((sample)(fp))(i);
+    }
+    return sum;
+}
+
+// callDoubles passes a double in each floating-point argument register around an integer.
+double callDoubles(double (*cb)(double, double, double, double, long, double, double, double, double), double x) {
+    return cb(x, x + 1, x + 2, x + 3, 100, x + 4, x + 5, x + 6, x + 7);
+}
+
+struct size {
+    int width, height;
+};
+
+struct point {
+    double x, y;
+};
+
+struct mixed {
+    float f;
+    long l;
+};
+
+struct big {
+    long a, b, c, d;
+};
+
+int callResize(int (*cb)(struct size), int width, int height) {
+    struct size s = {width, height};
+    return cb(s);
+}
+
+int callResizeOnStack(int (*cb)(long, long, long, long, long, long, long, long, struct size), int width, int height) {
+    struct size s = {width, height};
+    return cb(1, 2, 3, 4, 5, 6, 7, 8, s);
+}
+
+double callPoint(struct point (*cb)(struct point, double), double x, double y) {
+    struct point p = {x, y};
+    p = cb(p, 2);
+    return p.x * 10 + p.y;
+}
+
+long callMixed(struct mixed (*cb)(struct mixed), float f, long l) {
+    struct mixed m = {f, l};
+    m = cb(m);
+    return (long)(m.f * 100) + m.l;
+}
+
+long callBig(struct big (*cb)(struct big, long), long n) {
+    struct big b = {n, n + 1, n + 2, n + 3};
+    b = cb(b, 10);
+    return b.a + b.b * 10 + b.c * 100 + b.d * 1000;
+}
+
+// callFloats passes doubles and a struct point, which is a homogeneous floating-point aggregate,
+// in the floating-point registers.
+double callFloats(double (*cb)(double, struct point, double, float), double x, double y) {
+    struct point p = {x, y};
+    return cb(0.5, p, 0.25, 8);
+}
+
+// struct quad is a homogeneous floating-point aggregate of four doubles in nested arrays.
+struct quad {
+    struct {
+        double v[2];
+    } lo;
+    double hi[2];
+};
+
+double callQuad(struct quad (*cb)(double), double x) {
+    struct quad q = cb(x);
+    return q.lo.v[0] + q.lo.v[1] * 10 + q.hi[0] * 100 + q.hi[1] * 1000;
+}
+
+typedef int (*logger)(const char *, int);
+
+int callLogger(logger cb) {
+    return cb("disk full", 3) + cb(NULL, 0);
+}
+
+static long multiply(long a, long b) {
+    return a * b;
+}
+
+typedef long (*binop)(long, long);
+
+long callWithOp(long (*cb)(binop, long), int withOp) {
+    return cb(withOp ? multiply : NULL, 6);
+}
@@ -4,6 +4,7 @@
 #include <stdint.h>
 #include <stdio.h>
 #include <stdlib.h>
+#include <string.h>
 
 #if defined(__x86_64__) || defined(__aarch64__)
 typedef int64_t GoInt;
@@ -374,3 +375,188 @@ struct FourInt32s {
 int32_t FourInt32s(struct FourInt32s s) {
     return s.f0 + s.f1 + s.f2 + s.f3;
 }
+
+struct CharInt {
+    int8_t a;
+    int32_t b;
+};
+
+int32_t CharInt(struct CharInt s) {
+    return s.a + s.b;
+}
+
+struct IntLong {
+    int32_t a;
+    int64_t b;
+};
+
+int64_t IntLong(struct IntLong s) {
+    return s.a + s.b;
+}
+
+struct SixInt32s {
+    int32_t a, b, c, d, e, f;
+};
+
+int32_t SixInt32s(struct SixInt32s s) {
+    return s.a + s.b + s.c + s.d + s.e + s.f;
+}
+
+struct TwoInt64s {
+    int64_t x, y;
+};
+
+// TwoInt64sAfterFiveInts checks that a struct which doesn't fit in the remaining
+// integer registers is passed entirely on the stack while the following integer
+// still uses a register.
+int64_t TwoInt64sAfterFiveInts(int64_t a, int64_t b, int64_t c, int64_t d, int64_t e, struct TwoInt64s s, int64_t f) {
+    return a + b + c + d + e + s.x - s.y + f;
+}
+
+struct DoubleAndInt64 {
+    double x;
+    int64_t y;
+};
+
+double DoubleAndInt64AfterEightDoubles(double a, double b, double c, double d, double e, double f, double g, double h, struct DoubleAndInt64 s) {
+    return a + b + c + d + e + f + g + h + s.x + (double)s.y;
+}
+
+struct __attribute__((packed)) PackedCharInt32Double {
+    int8_t a;
+    int32_t b;
+    double c;
+};
+
+// PackedCharInt32Double has unaligned fields so it is passed in memory on amd64.
+int64_t PackedCharInt32Double(struct PackedCharInt32Double s) {
+    return s.a + s.b + (int64_t)s.c;
+}
+
+struct __attribute__((packed)) PackedFloats {
+    float a;
+    float b;
+    int8_t c;
+};
+
+float PackedFloats(struct PackedFloats s) {
+    return s.a + s.b + s.c;
+}
+
+struct ExplicitOffset {
+    int32_t a;
+    int32_t reserved[2];
+    int32_t b;
+};
+
+int32_t ExplicitOffset(struct ExplicitOffset s) {
+    return s.a - s.b;
+}
+
+struct Aligned16 {
+    _Alignas(16) int64_t a;
+    int64_t b;
+};
+
+// Aligned16AfterInt checks that a struct aligned to 16 bytes starts at an even register on arm64.
+int64_t Aligned16AfterInt(int64_t x, struct Aligned16 s) {
+    return x + s.a - s.b;
+}
+
+struct OverAligned {
+    int64_t a;
+    _Alignas(16) int64_t b;
+    int64_t c;
+};
+
+// OverAlignedAfterSevenInts checks that a struct aligned to 16 bytes is aligned on the stack.
+int64_t OverAlignedAfterSevenInts(int64_t a, int64_t b, int64_t c, int64_t d, int64_t e, int64_t f, int64_t g, struct OverAligned s) {
+    return a + b + c + d + e + f + g + s.a - s.b + s.c;
+}
+
+union IntOrDouble {
+    int64_t i;
+    double d;
+};
+
+// UnionIntOrDouble checks that a union with an integer member is passed in an integer register.
+int64_t UnionIntOrDouble(double a, union IntOrDouble u) {
+    return u.i + (int64_t)a;
+}
+
+union FloatOrFloats {
+    float f;
+    float fs[2];
+};
+
+// UnionFloats checks that a union of floats is passed in floating-point registers.
+float UnionFloats(union FloatOrFloats u) {
+    return u.fs[0] + u.fs[1];
+}
+
+struct Event {
+    int32_t type;
+    union {
+        int32_t key;
+        float x;
+    } data;
+    double time;
+};
+
+double EventKey(struct Event e) {
+    return e.type + e.data.key + e.time;
+}
+
+struct Named {
+    const char *name;
+    int32_t id;
+    _Bool enabled;
+};
+
+// NamedLength checks that a string field is passed as a char*.
+int64_t NamedLength(struct Named n) {
+    return n.enabled ? (int64_t)strlen(n.name) * n.id : -1;
+}
+
+struct Ops {
+    int64_t (*apply)(int64_t);
+    int64_t x;
+};
+
+// ApplyOps checks that a func field is passed as a function pointer.
+int64_t ApplyOps(struct Ops o) {
+    return o.apply(o.x);
+}
+
+struct Point {
+    float x, y;
+};
+
+struct Line {
+    struct Point pts[2];
+};
+
+// LineSum checks that an array of structs is flattened into an HFA on arm64
+// and into SSE eightbytes on amd64.
+float LineSum(struct Line l) {
+    return l.pts[0].x + l.pts[0].y + l.pts[1].x + l.pts[1].y;
+}
+
+struct Flag {
+    _Bool set;
+    int16_t value;
+};
+
+struct Flags {
+    struct Flag flags[3];
+};
+
+int64_t FlagsSum(struct Flags f) {
+    int64_t sum = 0;
+    for (int i = 0; i < 3; i++) {
+        if (f.flags[i].set) {
+            sum += f.flags[i].value;
+        }
+    }
+    return sum;
+}
@@ -236,3 +236,113 @@ struct Ptr1 ReturnPtr1(int64_t *a, void *b) {
     struct Ptr1 s = {a, b};
     return s;
 }
+
+struct CharInt{
+     int8_t a;
+     int32_t b;
+};
+
+struct CharInt ReturnCharInt(int8_t a, int32_t b) {
+    struct CharInt s = {a, b};
+    return s;
+}
+
+struct IntLong{
+     int32_t a;
+     int64_t b;
+};
+
+struct IntLong ReturnIntLong(int32_t a, int64_t b) {
+    struct IntLong s = {a, b};
+    return s;
+}
+
+struct IntDouble{
+     int32_t a;
+     double b;
+};
+
+struct IntDouble ReturnIntDouble(int32_t a, double b) {
+    struct IntDouble s = {a, b};
+    return s;
+}
+
+struct __attribute__((packed)) PackedCharInt {
+    int8_t a;
+    int32_t b;
+};
+
+struct PackedCharInt ReturnPackedCharInt(int8_t a, int32_t b) {
+    struct PackedCharInt s = {a, b};
+    return s;
+}
+
+struct ExplicitOffset {
+    int16_t a;
+    int16_t reserved[5];
+    int32_t b;
+    double c;
+};
+
+struct ExplicitOffset ReturnExplicitOffset(int16_t a, int32_t b, double c) {
+    struct ExplicitOffset s = {a, {0}, b, c};
+    return s;
+}
+
+union IntOrDouble {
+    int64_t i;
+    double d;
+};
+
+union IntOrDouble ReturnUnionDouble(double d) {
+    union IntOrDouble u;
+    u.d = d;
+    return u;
+}
+
+union FloatOrFloats {
+    float f;
+    float fs[2];
+};
+
+union FloatOrFloats ReturnUnionFloats(float a, float b) {
+    union FloatOrFloats u;
+    u.fs[0] = a;
+    u.fs[1] = b;
+    return u;
+}
+
+union Large {
+    int64_t a[3];
+    double d;
+};
+
+union Large ReturnUnionLarge(int64_t a, int64_t b, int64_t c) {
+    union Large u = {{a, b, c}};
+    return u;
+}
+
+struct Named {
+    const char *name;
+    int32_t id;
+    _Bool enabled;
+};
+
+struct Named ReturnNamed(int32_t id) {
+    struct Named n = {"purego", id, 1};
+    return n;
+}
+
+static int64_t twice(int64_t x) {
+    return 2 * x;
+}
+
+struct Ops {
+    int64_t (*apply)(int64_t);
+    int64_t x;
+};
+
+struct Ops ReturnOps(int64_t x) {
+    struct Ops o = {twice, x};
+    return o;
+}
@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+//go:build darwin || freebsd || linux || netbsd || windows
+
+package purego
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ParamError describes why a parameter of a function signature can't be used to call a C function.
+type ParamError struct {
+	// Index is the index of the argument or of the result if Result is set.
+	// It is -1 if the problem is with the signature as a whole like too many arguments.
+	Index  int
+	Result bool         // the parameter is a result
+	Kind   reflect.Kind // the kind of the parameter or reflect.Func for the whole signature
+	Reason string
+}
+
+func (e ParamError) Error() string {
+	switch {
+	case e.Index < 0:
+		return "function: " + e.Reason
+	case e.Result:
+		return fmt.Sprintf("result %d (%s): %s", e.Index, e.Kind, e.Reason)
+	default:
+		return fmt.Sprintf("argument %d (%s): %s", e.Index, e.Kind, e.Reason)
+	}
+}
+
+// SignatureError is returned by Validate, RegisterFuncE and RegisterLibFuncE when a function type
+// can't be used to call a C function. It lists every offending parameter.
+type SignatureError struct {
+	Type   reflect.Type // the function type or nil if fptr isn't a function pointer
+	Params []ParamError
+}
+
+func (e *SignatureError) Error() string {
+	problems := make([]string, len(e.Params))
+	for i, p := range e.Params {
+		problems[i] = p.Error()
+	}
+	return fmt.Sprintf("purego: unsupported signature %v: %s", e.Type, strings.Join(problems, "; "))
+}
+
+// Validate checks that fptr is a pointer to a function whose signature can be used with RegisterFunc
+// on this platform. It returns a *SignatureError listing every parameter that RegisterFunc would reject
+// or nil if there are none. Validate never panics.
+func Validate(fptr any) error {
+	v := reflect.ValueOf(fptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Func {
+		return &SignatureError{Params: []ParamError{{Index: -1, Kind: v.Kind(), Reason: "fptr must be a function pointer"}}}
+	}
+	ty := v.Elem().Type()
+	e := &SignatureError{Type: ty}
+	for i := 0; i < ty.NumIn(); i++ {
+		variadic := ty.IsVariadic() && i == ty.NumIn()-1
+		single := reflect.FuncOf([]reflect.Type{ty.In(i)}, nil, variadic)
+		if reason := catchPanic(func() { newCFunc(single, 0) }); reason != "" {
+			e.Params = append(e.Params, ParamError{Index: i, Kind: ty.In(i).Kind(), Reason: reason})
+		}
+	}
+	numOut := ty.NumOut()
+	if hasErrnoResult(ty) {
+		numOut--
+	}
+	for i := 0; i < numOut; i++ {
+		out := ty.Out(i)
+		var reason string
+		switch {
+		case i >= 2:
+			reason = "function can only return up to two values besides a trailing error"
+		case numOut >= 2:
+			reason = catchPanic(func() { checkPairResult(out) })
+		default:
+			single := reflect.FuncOf(nil, []reflect.Type{out}, false)
+			reason = catchPanic(func() { newCFunc(single, 0) })
+		}
+		if reason != "" {
+			e.Params = append(e.Params, ParamError{Index: i, Result: true, Kind: ty.Out(i).Kind(), Reason: reason})
+		}
+	}
+	if len(e.Params) == 0 {
+		// each parameter is fine on its own so check the limits of the whole signature
+		if reason := catchPanic(func() { newCFunc(ty, 0) }); reason != "" {
+			e.Params = append(e.Params, ParamError{Index: -1, Kind: reflect.Func, Reason: reason})
+		}
+	}
+	if len(e.Params) > 0 {
+		return e
+	}
+	return nil
+}
+
+// RegisterFuncE is like RegisterFunc but returns an error instead of panicking.
+// The error is a *SignatureError if the signature of fptr isn't supported.
+func RegisterFuncE(fptr any, cfn uintptr) error {
+	if err := Validate(fptr); err != nil {
+		return err
+	}
+	if cfn == 0 {
+		return errors.New("purego: cfn is nil")
+	}
+	if reason := catchPanic(func() { RegisterFunc(fptr, cfn) }); reason != "" {
+		return errors.New("purego: " + reason)
+	}
+	return nil
+}
+
+// RegisterLibFuncE is like RegisterLibFunc but returns an error instead of panicking.
+// The signature of fptr is validated before looking up the symbol.
+func RegisterLibFuncE(fptr any, handle uintptr, name string) error {
+	if err := Validate(fptr); err != nil {
+		return err
+	}
+	sym, err := loadSymbol(handle, name)
+	if err != nil {
+		return err
+	}
+	return RegisterFuncE(fptr, sym)
+}
+
+// catchPanic calls f and returns the message of its panic without the purego prefix
+// or an empty string if it didn't panic.
+func catchPanic(f func()) (reason string) {
+	defer func() {
+		if r := recover(); r != nil {
+			reason = strings.TrimPrefix(fmt.Sprint(r), "purego: ")
+		}
+	}()
+	f()
+	return ""
+}
@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: Apache-2.0
+// SPDX-FileCopyrightText: 2025 The Ebitengine Authors
+
+package purego_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ebitengine/purego"
+	"github.com/ebitengine/purego/internal/load"
+)
+
+func TestValidate(t *testing.T) {
+	var ok func(a int32, p *byte) (int64, error)
+	if err := purego.Validate(&ok); err != nil {
+		t.Errorf("Validate(%T) failed: %v", ok, err)
+	}
+
+	var bad func(a int32, b chan int, c uintptr, d map[int]int) (map[int]int, int, int)
+	err := purego.Validate(&bad)
+	var sigErr *purego.SignatureError
+	if !errors.As(err, &sigErr) {
+		t.Fatalf("Validate(%T) returned %v but wanted a *SignatureError", bad, err)
+	}
+	want := []struct {
+		index  int
+		result bool
+		kind   reflect.Kind
+	}{
+		{1, false, reflect.Chan},
+		{3, false, reflect.Map},
+		{0, true, reflect.Map},
+		{2, true, reflect.Int},
+	}
+	if len(sigErr.Params) != len(want) {
+		t.Fatalf("Validate(%T) reported %d problems but wanted %d: %v", bad, len(sigErr.Params), len(want), err)
+	}
+	for i, w := range want {
+		p := sigErr.Params[i]
+		if p.Index != w.index || p.Result != w.result || p.Kind != w.kind || p.Reason == "" {
+			t.Errorf("problem %d is %+v but wanted index %d, result %t and kind %s", i, p, w.index, w.result, w.kind)
+		}
+	}
+
+	if err := purego.Validate(bad); err == nil {
+		t.Errorf("Validate of a function that isn't a pointer succeeded")
+	}
+	if err := purego.Validate(nil); err == nil {
+		t.Errorf("Validate(nil) succeeded")
+	}
+}
+
+func TestRegisterFuncE(t *testing.T) {
+	var bad func(chan int)
+	if err := purego.RegisterFuncE(&bad, 1); err == nil || bad != nil {
+		t.Errorf("RegisterFuncE of an unsupported signature returned %v", err)
+	}
+	var fn func() int32
+	if err := purego.RegisterFuncE(&fn, 0); err == nil {
+		t.Errorf("RegisterFuncE with a nil cfn succeeded")
+	}
+
+	library, err := getSystemLibrary()
+	if err != nil {
+		t.Fatalf("couldn't get system library: %s", err)
+	}
+	libc, err := load.OpenLibrary(library)
+	if err != nil {
+		t.Fatalf("failed to dlopen: %s", err)
+	}
+	if err := purego.RegisterLibFuncE(&fn, libc, "purego_missing_symbol"); err == nil {
+		t.Errorf("RegisterLibFuncE of a missing symbol succeeded")
+	}
+	var abs func(int32) int32
+	if err := purego.RegisterLibFuncE(&abs, libc, "abs"); err != nil {
+		t.Fatalf("RegisterLibFuncE failed: %v", err)
+	}
+	if got := abs(-3); got != 3 {
+		t.Errorf("abs(-3) returned %d", got)
+	}
+}